@@ -2,46 +2,90 @@ package tunnel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
+	"github.com/rs/xid"
+
 	"github.com/codingLayce/tunnel.go/common/maps"
+	"github.com/codingLayce/tunnel.go/id"
 	"github.com/codingLayce/tunnel.go/pdu"
 	"github.com/codingLayce/tunnel.go/pdu/command"
 	"github.com/codingLayce/tunnel.go/tcp"
 )
 
-var (
-	waitForAckTimeout = 10 * time.Second
-)
-
 type TCPClient interface {
 	Connect() error
 	Stop()
 	Done() <-chan struct{}
 	Send(payload []byte) error
+	SetFraming(framing pdu.Framing)
 }
 
 type Client struct {
 	addr     string
 	internal TCPClient
 
-	// ackWaiters stores channel used to wait for an acknowledgement of the transaction_id (key).
-	// true is written when ack is received, false is written when nack is received.
-	ackWaiters *maps.SyncMap[string, chan bool]
+	// waiters stores channel used to wait for the response of the transaction_id (key).
+	// The response command (ack, nack or any command replying to the transaction) is written to it.
+	waiters *maps.SyncMap[string, chan command.Command]
+
+	// listeners stores the Subscription receiving the messages of the given tunnel name (key).
+	listeners *maps.SyncMap[string, *Subscription]
+
+	// tunnels stores the type of the Tunnels created by the Client by name (key), created again after a reconnection.
+	tunnels *maps.SyncMap[string, command.TunnelType]
 
-	// listeners stores channel used to receive message from the given tunnel name (key).
-	// The raw string message is passed to the channel.
-	// /!\ Currently there is no way to stop listening /!\
-	listeners *maps.SyncMap[string, chan string]
+	// commandHandlers stores the handler of the server-initiated commands unknown to the SDK by indicator (key).
+	commandHandlers *maps.SyncMap[byte, CommandHandler]
 
 	ctx    context.Context
-	stopFn context.CancelFunc
+	stopFn context.CancelCauseFunc
 	wg     sync.WaitGroup
 	mtx    sync.Mutex
 
+	// capabilities agreed with the server during the last handshake, guarded by mtx.
+	capabilities Capabilities
+	framing      pdu.Framing
+	compression  pdu.Compression
+	// compressionThreshold is the minimum data size (bytes) of the compressed commands, guarded by mtx.
+	compressionThreshold int
+
+	// replies stores the channel used to wait for the reply of the request's correlation id (key).
+	replies *maps.SyncMap[string, chan *Message]
+	// replyFuture is the creation of the private Tunnel receiving the replies, started by the first request.
+	// Guarded by replyMtx.
+	replyFuture *replyTunnelFuture
+	replyMtx    sync.Mutex
+
+	// ids generates the transaction ids of the commands sent by the Client, guarded by mtx.
+	ids id.Generator
+
+	// heartbeatInterval is guarded by mtx, heartbeatReset wakes up the heartbeat routine when it changes.
+	heartbeatInterval time.Duration
+	heartbeatReset    chan struct{}
+
+	// ready is closed once the Client is connected (handshake and restoration done), a new one being created with
+	// each internal client. Guarded by mtx.
+	ready chan struct{}
+
+	// Configuration set by the Options.
+	ackTimeout     time.Duration
+	requestTimeout time.Duration
+	readTimeout    time.Duration
+	stopTimeout    time.Duration
+	dialer         tcp.Dialer
+	retryPolicy    RetryPolicy
+	name           string
+	hooks          Hooks
+	// waitForReconnect makes the operations wait for the reconnection instead of failing with ErrNotConnected.
+	waitForReconnect bool
+
+	states stateMachine
+
 	Logger *slog.Logger
 }
 
@@ -49,28 +93,63 @@ type Client struct {
 // You must call Stop method when the client is no longer needed (to gracefully wait for the internal routines to finish).
 //
 // Internally the Client is going to keep the connection with the server active (by retrying to connect with the Tunnel server if the connection is lost).
-func Connect(addr string) (*Client, error) {
-	// TODO: Configurations for logger
-
+// The Options configure the Client, i.e. Connect(addr, WithAckTimeout(time.Second), WithClientName("billing")).
+func Connect(addr string, opts ...Option) (*Client, error) {
 	client := &Client{
-		addr:       addr,
-		Logger:     slog.Default().With("entity", "TUNNEL_CLIENT"),
-		ackWaiters: maps.NewSyncMap[string, chan bool](),
-		listeners:  maps.NewSyncMap[string, chan string](),
-	}
-	client.internal = newTCPClient(&tcp.ClientOption{
-		Addr:      addr,
-		OnPayload: client.onPayload,
-	})
+		addr:            addr,
+		Logger:          slog.Default().With("entity", "TUNNEL_CLIENT"),
+		waiters:         maps.NewSyncMap[string, chan command.Command](),
+		listeners:       maps.NewSyncMap[string, *Subscription](),
+		tunnels:         maps.NewSyncMap[string, command.TunnelType](),
+		commandHandlers: maps.NewSyncMap[byte, CommandHandler](),
+		replies:         maps.NewSyncMap[string, chan *Message](),
+		ids:             id.NewMonotonicGenerator(),
+
+		compressionThreshold: defaultCompressionThreshold,
+
+		heartbeatInterval: defaultHeartbeatInterval,
+		heartbeatReset:    make(chan struct{}, 1),
+		ready:             make(chan struct{}),
+
+		ackTimeout:     defaultAckTimeout,
+		requestTimeout: defaultRequestTimeout,
+		readTimeout:    defaultReadTimeout,
+		stopTimeout:    defaultStopTimeout,
+		retryPolicy:    defaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	err := client.validate()
+	if err != nil {
+		return nil, fmt.Errorf("connect to Tunnel server: %w", err)
+	}
+	client.internal = client.newInternal()
+	client.states.hook = client.hooks.OnStateChange
+
+	client.ctx, client.stopFn = context.WithCancelCause(context.Background())
+
+	err = client.internal.Connect()
+	if err != nil {
+		client.stopFn(ErrClientStopped)
+		client.setState(StateStopped, 0, err)
+		return nil, fmt.Errorf("connect to Tunnel server: %w", err)
+	}
 
-	err := client.internal.Connect()
+	err = client.handshake()
 	if err != nil {
+		client.stopFn(ErrClientStopped)
+		client.internal.Stop()
+		client.setState(StateStopped, 0, err)
 		return nil, fmt.Errorf("connect to Tunnel server: %w", err)
 	}
 
-	client.Logger.Info("Connected to Tunnel server")
+	client.Logger.Info("Connected to Tunnel server", "protocol_version", client.Capabilities().Version)
+
+	client.startHeartbeat()
+	client.connected()
 
-	client.ctx, client.stopFn = context.WithCancel(context.Background())
+	client.wg.Add(1)
 	go client.keepConnectedLoop()
 
 	return client, nil
@@ -79,131 +158,523 @@ func Connect(addr string) (*Client, error) {
 // Stop stops the internal client.
 // Client is no longer usable after stopping it.
 func (c *Client) Stop() {
-	c.stopFn()
+	c.deleteReplyTunnel()
+	c.cancel(ErrClientStopped)
 	c.wg.Wait()
+	c.setState(StateStopped, 0, ErrClientStopped)
 	c.Logger.Info("Stopped")
 }
 
+// Done is closed when the Client is stopped, either by Stop or because it gave up reconnecting (see RetryPolicy).
+func (c *Client) Done() <-chan struct{} {
+	return c.ctx.Done()
+}
+
+// Err returns the reason why the Client is stopped, nil while it is running:
+//   - ErrClientStopped after Stop
+//   - ErrReconnectGaveUp when the RetryPolicy gave up reconnecting, wrapping the last connection error
+//
+// Stop must still be called after giving up, to release the resources.
+func (c *Client) Err() error {
+	if c.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(c.ctx)
+}
+
 // PublishMessage publishes the given message to the given Tunnel.
 // Returns an error if the server doesn't accept the message.
 func (c *Client) PublishMessage(tunnelName, message string) error {
-	cmd := command.NewPublishMessage(tunnelName, message)
-	err := c.sendCommand(cmd)
-	if err != nil {
-		return err
+	return c.PublishMessageContext(context.Background(), tunnelName, message)
+}
+
+// PublishMessageContext is PublishMessage, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) PublishMessageContext(ctx context.Context, tunnelName, message string) error {
+	return c.PublishBytesContext(ctx, tunnelName, []byte(message))
+}
+
+// PublishBytes publishes the given raw message to the given Tunnel.
+// Returns an error if the server doesn't accept the message.
+func (c *Client) PublishBytes(tunnelName string, message []byte) error {
+	return c.PublishBytesContext(context.Background(), tunnelName, message)
+}
+
+// PublishBytesContext is PublishBytes, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) PublishBytesContext(ctx context.Context, tunnelName string, message []byte) error {
+	return c.PublishWithHeadersContext(ctx, tunnelName, message, nil)
+}
+
+// PublishWithHeaders publishes the given raw message along with its headers to the given Tunnel.
+// Returns ErrUnsupportedFeature if the server doesn't support the headers,
+// otherwise an error if the server doesn't accept the message.
+func (c *Client) PublishWithHeaders(tunnelName string, message []byte, headers map[string]string) error {
+	return c.PublishWithHeadersContext(context.Background(), tunnelName, message, headers)
+}
+
+// PublishWithHeadersContext is PublishWithHeaders, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) PublishWithHeadersContext(ctx context.Context, tunnelName string, message []byte, headers map[string]string) error {
+	return c.PublishContext(ctx, &Message{TunnelName: tunnelName, Headers: headers, Body: message})
+}
+
+// Publish publishes the Message to its Tunnel.
+// When empty, the Message's ID is generated before publishing it if the server supports the message ids.
+// Returns ErrUnsupportedFeature if the Message has headers or an ID and the server doesn't support them,
+// otherwise an error if the server doesn't accept the message.
+func (c *Client) Publish(msg *Message) error {
+	return c.PublishContext(context.Background(), msg)
+}
+
+// PublishContext is Publish, returning ctx.Err() if the context is done before the server's response.
+// The message may have been published anyway.
+func (c *Client) PublishContext(ctx context.Context, msg *Message) error {
+	_, err := c.publish(ctx, msg)
+	return err
+}
+
+func (c *Client) publish(ctx context.Context, msg *Message) (TCPClient, error) {
+	// Publishes the Message and returns the connection it has been published on.
+	capabilities := c.Capabilities()
+	if len(msg.Headers) > 0 && !capabilities.Has(command.HeadersCapability) {
+		err := fmt.Errorf("%w: %s", ErrUnsupportedFeature, command.HeadersCapability)
+		c.Logger.Error("Cannot publish message", "tunnel_name", msg.TunnelName, "error", err)
+		return nil, err
+	}
+	if msg.ID != "" && !capabilities.Has(command.MessageIDCapability) {
+		err := fmt.Errorf("%w: %s", ErrUnsupportedFeature, command.MessageIDCapability)
+		c.Logger.Error("Cannot publish message", "tunnel_name", msg.TunnelName, "message_id", msg.ID, "error", err)
+		return nil, err
+	}
+	if msg.ID == "" && capabilities.Has(command.MessageIDCapability) {
+		msg.ID = xid.New().String()
 	}
 
-	err = c.waitAck(cmd.TransactionID())
+	cmd := command.NewPublishMessageWithTransactionID(c.newTransactionID(), msg.TunnelName, msg.Body)
+	cmd.MessageID = msg.ID
+	cmd.Headers = msg.Headers
+	response, internal, err := c.roundTrip(ctx, cmd, c.ackTimeout, c.sendCommand)
+	err = ackResult(response, err)
 	if err != nil {
-		// TODO: Better error handling (typed error returned to client)
-		c.Logger.Error("Error waiting for ack", "error", err)
-		return err
+		c.Logger.Error("Cannot publish message", "tunnel_name", msg.TunnelName, "message_id", msg.ID, "error", err)
+		return nil, err
 	}
 
-	c.Logger.Info("Message published to Tunnel", "tunnel_name", tunnelName)
+	c.Logger.Info("Message published to Tunnel", "tunnel_name", msg.TunnelName, "message_id", msg.ID)
 
-	return nil
+	return internal, nil
 }
 
 // ListenTunnel makes the client listening for the given Tunnel's name messages.
 // When a message is received, the callback function is invoked.
-func (c *Client) ListenTunnel(name string, callback func(string)) error {
-	cmd := command.NewListenTunnel(name)
-	err := c.sendCommand(cmd)
-	if err != nil {
-		// TODO: Better error handling (typed error returned to client)
-		return err
+// The returned Subscription allows to stop listening.
+//
+// The name can be a pattern matching several Topic Tunnels, a "*" segment matches exactly one segment
+// and a trailing ">" segment matches one or more segments (i.e. "orders.*.created" or "orders.>").
+func (c *Client) ListenTunnel(name string, callback func(string)) (*Subscription, error) {
+	return c.ListenTunnelContext(context.Background(), name, callback)
+}
+
+// ListenTunnelContext is ListenTunnel, returning ctx.Err() if the context is done before the server's response.
+// The context only applies to the listen request, not to the returned Subscription.
+func (c *Client) ListenTunnelContext(ctx context.Context, name string, callback func(string)) (*Subscription, error) {
+	return c.ListenTunnelBytesContext(ctx, name, func(message []byte) {
+		callback(string(message))
+	})
+}
+
+// ListenTunnelBytes makes the client listening for the given Tunnel's name messages.
+// When a message is received, the callback function is invoked with the raw message.
+// The returned Subscription allows to stop listening.
+func (c *Client) ListenTunnelBytes(name string, callback func([]byte)) (*Subscription, error) {
+	return c.ListenTunnelBytesContext(context.Background(), name, callback)
+}
+
+// ListenTunnelBytesContext is ListenTunnelBytes, returning ctx.Err() if the context is done before the server's response.
+// The context only applies to the listen request, not to the returned Subscription.
+func (c *Client) ListenTunnelBytesContext(ctx context.Context, name string, callback func([]byte)) (*Subscription, error) {
+	return c.ListenTunnelMessagesContext(ctx, name, func(message *Message) {
+		callback(message.Body)
+	})
+}
+
+// ListenTunnelMessages makes the client listening for the given Tunnel's name messages.
+// When a message is received, the callback function is invoked with the whole Message (body and headers).
+// The message is acknowledged once the callback returns (see ListenTunnelHandler to nack it).
+// The returned Subscription allows to stop listening.
+// Returns ErrAlreadyListening if the client is already listening the Tunnel,
+// ErrUnsupportedFeature if the name is a pattern and the server doesn't support Topic Tunnels.
+func (c *Client) ListenTunnelMessages(name string, callback func(*Message)) (*Subscription, error) {
+	return c.ListenTunnelMessagesContext(context.Background(), name, callback)
+}
+
+// ListenTunnelMessagesContext is ListenTunnelMessages, returning ctx.Err() if the context is done before the server's
+// response. The context only applies to the listen request, not to the returned Subscription.
+func (c *Client) ListenTunnelMessagesContext(ctx context.Context, name string, callback func(*Message)) (*Subscription, error) {
+	return c.ListenTunnelHandlerContext(ctx, name, func(message *Message) error {
+		callback(message)
+		return nil
+	})
+}
+
+// ListenTunnelHandler makes the client listening for the given Tunnel's name messages.
+// When a message is received, the handler is invoked with the whole Message and the server is acknowledged
+// with its result once it returns (see MessageHandler), allowing at-least-once processing.
+// The returned Subscription allows to stop listening.
+// Returns ErrAlreadyListening if the client is already listening the Tunnel,
+// ErrUnsupportedFeature if the name is a pattern and the server doesn't support Topic Tunnels.
+func (c *Client) ListenTunnelHandler(name string, handler MessageHandler) (*Subscription, error) {
+	return c.ListenTunnelHandlerContext(context.Background(), name, handler)
+}
+
+// ListenTunnelHandlerContext is ListenTunnelHandler, returning ctx.Err() if the context is done before the server's
+// response. The context only applies to the listen request, not to the returned Subscription.
+func (c *Client) ListenTunnelHandlerContext(ctx context.Context, name string, handler MessageHandler) (*Subscription, error) {
+	if command.IsTopicPattern(name) && !c.Capabilities().Has(command.TopicTunnelCapability) {
+		err := fmt.Errorf("%w: %s", ErrUnsupportedFeature, command.TopicTunnelCapability)
+		c.Logger.Error("Cannot listen Tunnel", "tunnel_name", name, "error", err)
+		return nil, err
 	}
 
-	err = c.waitAck(cmd.TransactionID())
+	// The Subscription is stored before sending so the messages following the ack cannot be missed.
+	sub := newSubscription(c, name)
+	if !c.listeners.PutIfAbsent(name, sub) {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyListening, name)
+	}
+
+	cmd := command.NewListenTunnelWithTransactionID(c.newTransactionID(), name)
+	err := c.sendCommandAndWaitAck(ctx, cmd)
 	if err != nil {
-		// TODO: Better error handling (typed error returned to client)
-		c.Logger.Error("Error waiting for ack", "error", err)
-		return err
+		sub.end(err)
+		c.Logger.Error("Cannot listen Tunnel", "tunnel_name", name, "error", err)
+		return nil, err
 	}
 
 	c.wg.Add(1)
-	go c.listenTunnel(name, callback)
+	go sub.listen(handler)
 
 	c.Logger.Info("Listening to Tunnel", "tunnel_name", name)
 
-	return nil
+	return sub, nil
 }
 
 // CreateBTunnel asks the server to create a new Broadcast Tunnel.
+// Every message published to a Broadcast Tunnel is transferred to all its listeners.
 // Returns an error if the name is invalid or if the server nack the request.
 func (c *Client) CreateBTunnel(name string) error {
-	cmd := command.NewCreateTunnel(name)
-	cmd.Type = command.BroadcastTunnel
+	return c.CreateBTunnelContext(context.Background(), name)
+}
+
+// CreateBTunnelContext is CreateBTunnel, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) CreateBTunnelContext(ctx context.Context, name string) error {
+	return c.createTunnel(ctx, name, command.BroadcastTunnel)
+}
+
+// CreateQTunnel asks the server to create a new Queue Tunnel.
+// Every message published to a Queue Tunnel is transferred to a single listener (competing consumers).
+// Returns ErrUnsupportedFeature if the server doesn't support Queue Tunnels,
+// an error if the name is invalid or if the server nack the request.
+func (c *Client) CreateQTunnel(name string) error {
+	return c.CreateQTunnelContext(context.Background(), name)
+}
+
+// CreateQTunnelContext is CreateQTunnel, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) CreateQTunnelContext(ctx context.Context, name string) error {
+	if !c.Capabilities().Has(command.QueueTunnelCapability) {
+		err := fmt.Errorf("%w: %s", ErrUnsupportedFeature, command.QueueTunnelCapability)
+		c.Logger.Error("Cannot create Tunnel", "tunnel_name", name, "error", err)
+		return err
+	}
+	return c.createTunnel(ctx, name, command.QueueTunnel)
+}
+
+// CreateTTunnel asks the server to create a new Topic Tunnel.
+// The name of a Topic Tunnel is made of dot separated segments (i.e. "orders.eu.created"),
+// allowing to listen several Topic Tunnels at once with a wildcard pattern (see ListenTunnel).
+// Returns ErrUnsupportedFeature if the server doesn't support Topic Tunnels,
+// an error if the name is invalid or if the server nack the request.
+func (c *Client) CreateTTunnel(name string) error {
+	return c.CreateTTunnelContext(context.Background(), name)
+}
+
+// CreateTTunnelContext is CreateTTunnel, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) CreateTTunnelContext(ctx context.Context, name string) error {
+	if !c.Capabilities().Has(command.TopicTunnelCapability) {
+		err := fmt.Errorf("%w: %s", ErrUnsupportedFeature, command.TopicTunnelCapability)
+		c.Logger.Error("Cannot create Tunnel", "tunnel_name", name, "error", err)
+		return err
+	}
+	return c.createTunnel(ctx, name, command.TopicTunnel)
+}
+
+func (c *Client) createTunnel(ctx context.Context, name string, tunnelType command.TunnelType) error {
+	cmd := command.NewCreateTunnelWithTransactionID(c.newTransactionID(), name)
+	cmd.Type = tunnelType
 
-	err := c.sendCommand(cmd)
+	err := c.sendCommandAndWaitAck(ctx, cmd)
+	if errors.Is(err, ErrTunnelExists) {
+		c.tunnels.Put(name, tunnelType) // Declared anyway, the server may lose it
+	}
 	if err != nil {
-		// TODO: Better error handling (typed error returned to client)
+		c.Logger.Error("Cannot create Tunnel", "tunnel_name", name, "error", err)
 		return err
 	}
+	c.tunnels.Put(name, tunnelType)
+
+	c.Logger.Info("Tunnel created", "tunnel_name", name, "tunnel_type", tunnelType)
 
-	err = c.waitAck(cmd.TransactionID())
+	return nil
+}
+
+// DeleteTunnel asks the server to delete the Tunnel.
+// The listeners of the Tunnel are notified, and their Subscription ends with ErrTunnelDeleted.
+// Returns an error if the name is invalid or if the server nack the request.
+func (c *Client) DeleteTunnel(name string) error {
+	return c.DeleteTunnelContext(context.Background(), name)
+}
+
+// DeleteTunnelContext is DeleteTunnel, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) DeleteTunnelContext(ctx context.Context, name string) error {
+	err := c.sendCommandAndWaitAck(ctx, command.NewDeleteTunnelWithTransactionID(c.newTransactionID(), name))
 	if err != nil {
-		// TODO: Better error handling (typed error returned to client)
-		c.Logger.Error("Error waiting for ack", "error", err)
+		c.Logger.Error("Cannot delete Tunnel", "tunnel_name", name, "error", err)
 		return err
 	}
 
-	c.Logger.Info("Broadcast Tunnel created")
+	c.tunnels.Delete(name)
+	c.endSubscription(name, ErrTunnelDeleted)
+
+	c.Logger.Info("Tunnel deleted", "tunnel_name", name)
 
 	return nil
 }
 
-func (c *Client) listenTunnel(tunnelName string, callback func(string)) {
-	defer c.wg.Done()
-	msgCh := make(chan string)
-	c.listeners.Put(tunnelName, msgCh)
+// ListTunnels asks the server for the existing Tunnels.
+// The pattern filters the Tunnels by name, '*' matching any sequence of characters. An empty pattern lists all the Tunnels.
+func (c *Client) ListTunnels(pattern string) ([]TunnelInfo, error) {
+	return c.ListTunnelsContext(context.Background(), pattern)
+}
 
-	for {
-		select {
-		case msg := <-msgCh:
-			c.Logger.Debug("Received message", "tunnel_name", tunnelName, "message", msg)
-			callback(msg)
-			// TODO: Refactor callback to returns status of the message (processed or not) in order to reply accordingly.
-		case <-c.ctx.Done():
-			c.Logger.Debug("Stop listening Tunnel", "tunnel_name", tunnelName)
-			return
-		}
+// ListTunnelsContext is ListTunnels, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) ListTunnelsContext(ctx context.Context, pattern string) ([]TunnelInfo, error) {
+	info, err := c.sendCommandAndWaitTunnelInfo(ctx, command.NewListTunnelsWithTransactionID(c.newTransactionID(), pattern))
+	if err != nil {
+		c.Logger.Error("Cannot list Tunnels", "pattern", pattern, "error", err)
+		return nil, err
+	}
+
+	return newTunnelInfos(info), nil
+}
+
+// DescribeTunnel asks the server for the description of the Tunnel.
+// Returns ErrTunnelNotFound if the Tunnel doesn't exist.
+func (c *Client) DescribeTunnel(name string) (*TunnelInfo, error) {
+	return c.DescribeTunnelContext(context.Background(), name)
+}
+
+// DescribeTunnelContext is DescribeTunnel, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) DescribeTunnelContext(ctx context.Context, name string) (*TunnelInfo, error) {
+	info, err := c.sendCommandAndWaitTunnelInfo(ctx, command.NewDescribeTunnelWithTransactionID(c.newTransactionID(), name))
+	if err != nil {
+		c.Logger.Error("Cannot describe Tunnel", "tunnel_name", name, "error", err)
+		return nil, err
+	}
+
+	infos := newTunnelInfos(info)
+	if len(infos) != 1 {
+		err = fmt.Errorf("unexpected response %s", info.Info())
+		c.Logger.Error("Cannot describe Tunnel", "tunnel_name", name, "error", err)
+		return nil, err
+	}
+	return &infos[0], nil
+}
+
+func (c *Client) sendCommandAndWaitAck(ctx context.Context, cmd command.Command) error {
+	return ackResult(c.sendCommandAndWaitResponse(ctx, cmd))
+}
+
+func ackResult(response command.Command, err error) error {
+	// Returns nil when the response is an ack, the NackError of a nack.
+	if err != nil {
+		return err
+	}
+
+	switch castedResponse := response.(type) {
+	case *command.Ack:
+		return nil
+	case *command.Nack:
+		return newNackError(castedResponse)
+	default:
+		return fmt.Errorf("unexpected response %s", response.Info())
 	}
 }
 
-func (c *Client) waitAck(transactionID string) error {
-	ackCh := make(chan bool)
-	c.ackWaiters.Put(transactionID, ackCh)
-	defer c.unstoreAckWaiter(transactionID)
+func (c *Client) sendCommandAndWaitTunnelInfo(ctx context.Context, cmd command.Command) (*command.TunnelInfo, error) {
+	response, err := c.sendCommandAndWaitResponse(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	switch castedResponse := response.(type) {
+	case *command.TunnelInfo:
+		return castedResponse, nil
+	case *command.Nack:
+		return nil, newNackError(castedResponse)
+	default:
+		return nil, fmt.Errorf("unexpected response %s", response.Info())
+	}
+}
+
+func (c *Client) sendCommandAndWaitResponse(ctx context.Context, cmd command.Command) (command.Command, error) {
+	return c.sendCommandAndWaitResponseTimeout(ctx, cmd, c.ackTimeout)
+}
+
+func (c *Client) sendCommandAndWaitResponseTimeout(ctx context.Context, cmd command.Command, timeout time.Duration) (command.Command, error) {
+	return c.exchange(ctx, cmd, timeout, c.sendCommand)
+}
+
+func (c *Client) exchange(ctx context.Context, cmd command.Command, timeout time.Duration,
+	send func(ctx context.Context, cmd command.Command) (TCPClient, error),
+) (command.Command, error) {
+	response, _, err := c.roundTrip(ctx, cmd, timeout, send)
+	return response, err
+}
+
+func (c *Client) roundTrip(ctx context.Context, cmd command.Command, timeout time.Duration,
+	send func(ctx context.Context, cmd command.Command) (TCPClient, error),
+) (command.Command, TCPClient, error) {
+	// Sends the command with the send function (sendCommand or transmit) and waits for the server's response
+	// on the connection the command has been sent on, which is returned.
+	// The waiter is stored before sending so the response cannot be missed.
+	// A transaction id still pending is refused, the responses would be mixed up.
+	responseCh := make(chan command.Command, 1)
+	if !c.waiters.PutIfAbsent(cmd.TransactionID(), responseCh) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrDuplicateTransactionID, cmd.TransactionID())
+	}
+	defer c.waiters.Delete(cmd.TransactionID())
+
+	internal, err := send(ctx, cmd)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	select {
-	case ack := <-ackCh:
-		if ack {
-			return nil
+	case response := <-responseCh:
+		return response, internal, nil
+	case <-internal.Done():
+		select { // The response may have been received right before.
+		case response := <-responseCh:
+			return response, internal, nil
+		default:
+			return nil, nil, ErrConnectionLost
 		}
-		return fmt.Errorf("server nack")
-	case <-time.After(waitForAckTimeout):
-		return fmt.Errorf("timeout waiting for server acknowledgement")
+	case <-time.After(timeout):
+		return nil, nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, nil, c.Err()
+	}
+}
+
+// SetIDGenerator changes the generator of the transaction ids of the commands sent by the Client.
+// By default, the ids are sequential (see id.MonotonicGenerator). The commands whose generated id is invalid (see id.IsValid)
+// are not sent.
+func (c *Client) SetIDGenerator(generator id.Generator) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.ids = generator
+}
+
+func (c *Client) newTransactionID() string {
+	c.mtx.Lock()
+	generator := c.ids
+	c.mtx.Unlock()
+	return generator.New()
+}
+
+func (c *Client) sendCommand(ctx context.Context, cmd command.Command) (TCPClient, error) {
+	// Sends the command of an operation, once the Client is connected (see waitReady).
+	err := checkCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
 	}
+
+	err = c.waitReady(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("send command: %w", err)
+	}
+
+	return c.write(ctx, cmd)
 }
 
-func (c *Client) sendCommand(cmd command.Command) error {
-	err := cmd.Validate()
+func (c *Client) transmit(ctx context.Context, cmd command.Command) (TCPClient, error) {
+	// Sends the command on the current connection without waiting for the Client to be connected.
+	// Used by the handshake and the restoration (making the Client connected), and to respond to the server.
+	err := checkCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.write(ctx, cmd)
+}
+
+func checkCommand(ctx context.Context, cmd command.Command) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	// The transaction ids may come from a user-supplied Generator (see SetIDGenerator).
+	if !id.IsValid(cmd.TransactionID()) {
+		return fmt.Errorf("validate command: invalid transaction id %q", cmd.TransactionID())
+	}
+
+	err = cmd.Validate()
 	if err != nil {
 		return fmt.Errorf("validate command: %w", err)
 	}
+	return nil
+}
+
+func (c *Client) write(ctx context.Context, cmd command.Command) (TCPClient, error) {
+	// Frames the command and sends it on the current connection, which is returned.
+	// The payload is framed for the connection it's sent on.
+	c.mtx.Lock()
+	internal, framing, maxFrameSize := c.internal, c.framing, c.capabilities.MaxFrameSize
+	compression, compressionThreshold := c.compression, c.compressionThreshold
+	c.mtx.Unlock()
+
+	err := framing.Check(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("frame command: %w", err)
+	}
 
-	payload := pdu.Marshal(cmd)
+	if compressionThreshold <= 0 {
+		compression = pdu.NoCompression
+	}
+	payload, err := framing.MarshalCompressed(cmd, compression, compressionThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("frame command: %w", err)
+	}
+	if maxFrameSize != 0 && len(payload) > int(maxFrameSize) {
+		return nil, fmt.Errorf("frame command: payload size %d exceeds the max frame size %d", len(payload), maxFrameSize)
+	}
 	c.Logger.Debug("Sending payload", "payload", payload)
 
-	err = c.internal.Send(payload)
+	select {
+	case <-c.ctx.Done():
+		return nil, fmt.Errorf("send command: %w", c.Err())
+	case <-internal.Done():
+		return nil, fmt.Errorf("send command: %w", ErrNotConnected)
+	default:
+	}
+
+	err = send(ctx, internal, payload)
 	if err != nil {
-		return fmt.Errorf("send command: %w", err)
+		return nil, err
 	}
 
 	c.Logger.Debug("Sent command", "transaction_id", cmd.TransactionID(), "command", cmd.Info())
-	return nil
+	return internal, nil
 }
 
 func (c *Client) onPayload(payload []byte) {
@@ -212,67 +683,126 @@ func (c *Client) onPayload(payload []byte) {
 
 	c.Logger.Debug("Received payload", "payload", payload)
 
-	cmd, err := pdu.Unmarshal(payload)
+	c.mtx.Lock()
+	framing, compression := c.framing, c.compression
+	c.mtx.Unlock()
+
+	cmd, err := framing.UnmarshalCompressed(payload, compression, maxFrameSize)
 	if err != nil {
 		c.Logger.Warn("Received unparsable payload. Discarding it.", "error", err)
+		c.rejectPayload(payload, err)
 		return
 	}
 
 	c.Logger.Debug("Received command", "transaction_id", cmd.TransactionID(), "command", cmd.Info())
 
 	switch castedCMD := cmd.(type) {
-	case *command.Ack:
-		c.acknowledgementReceived(cmd.TransactionID(), true)
-	case *command.Nack:
-		c.acknowledgementReceived(cmd.TransactionID(), false)
+	case *command.Ack, *command.Nack, *command.TunnelInfo, *command.Pong:
+		c.responseReceived(cmd)
+	case *command.Hello:
+		c.helloReceived(castedCMD)
 	case *command.ReceiveMessage:
 		c.messageReceived(castedCMD)
-	default:
+	case *command.DeleteTunnel:
+		c.tunnelDeleted(castedCMD)
+	case *command.Ping:
+		c.pingReceived(castedCMD)
+	case *command.CreateTunnel, *command.ListenTunnel, *command.UnlistenTunnel, *command.PublishMessage,
+		*command.ListTunnels, *command.DescribeTunnel:
 		c.Logger.Warn("Received unsupported command", "command", cmd.Info())
+		c.rejectCommand(cmd.TransactionID(), command.NackUnsupportedCommand, "command not supported by the client")
+	default:
+		c.commandReceived(cmd)
 	}
 }
 
-func (c *Client) acknowledgementReceived(transactionID string, isAck bool) {
-	waiter, ok := c.ackWaiters.Get(transactionID)
+func (c *Client) responseReceived(cmd command.Command) {
+	waiter, ok := c.waiters.Get(cmd.TransactionID())
 	if !ok {
-		c.Logger.Warn("Received unexpected acknowledgement. Discarding it.")
+		c.Logger.Warn("Received unexpected response. Discarding it.", "command", cmd.Info())
 		return
 	}
-	select {
-	case waiter <- isAck:
-	case <-c.ctx.Done():
+	select { // The waiter is buffered, only the first response is kept.
+	case waiter <- cmd:
+	default:
 	}
 }
 
 func (c *Client) messageReceived(cmd *command.ReceiveMessage) {
-	ch, ok := c.listeners.Get(cmd.TunnelName)
-	if !ok {
+	subs := c.matchingSubscriptions(cmd.TunnelName)
+	if len(subs) == 0 {
 		c.Logger.Error("No listener for the received message", "tunnel_name", cmd.TunnelName)
 		return
 	}
-	select { // Prevent blocking when the Client is stopped.
-	case ch <- cmd.Message:
-		// TODO: Refacto to actually use the client's callback response to reply accordingly.
-		// Currently, always ack
-		err := c.sendCommand(command.NewAckWithTransactionID(cmd.TransactionID()))
+
+	// Acknowledged once every handler has returned, not acknowledged if no Subscription has received it.
+	s := &settlement{client: c, transactionID: cmd.TransactionID(), lost: c.connectionLost(), remaining: len(subs)}
+	for _, sub := range subs {
+		// Queued without blocking, the reading of the connection (and the heartbeat) must go on while the handlers run.
+		msg := &delivery{message: newMessage(cmd), settlement: s}
+		if !sub.push(msg) {
+			msg.drop()
+		}
+	}
+}
+
+func (c *Client) matchingSubscriptions(tunnelName string) []*Subscription {
+	// The server sends a message once per client, even if several of its Subscriptions match the Tunnel.
+	// Collected first so the messages are delivered without holding the listeners lock.
+	var subs []*Subscription
+	c.listeners.Foreach(func(name string, sub *Subscription) {
+		if name == tunnelName || (command.IsTopicPattern(name) && command.MatchTopic(name, tunnelName)) {
+			subs = append(subs, sub)
+		}
+	})
+	return subs
+}
+
+func (c *Client) tunnelDeleted(cmd *command.DeleteTunnel) {
+	// Notification from the server: the listened Tunnel has been deleted.
+	c.Logger.Info("Listened Tunnel deleted", "tunnel_name", cmd.Name)
+	c.tunnels.Delete(cmd.Name)
+	c.endSubscription(cmd.Name, ErrTunnelDeleted)
+
+	_, err := c.transmit(context.Background(), command.NewAckWithTransactionID(cmd.TransactionID()))
+	if err != nil {
+		c.Logger.Warn("Cannot ack the deletion", "error", err, "transaction_id", cmd.TransactionID())
+	}
+}
+
+func send(ctx context.Context, internal TCPClient, payload []byte) error {
+	// Sends the payload, giving up when the context is done. A payload being written isn't interrupted.
+	if ctx.Done() == nil {
+		err := internal.Send(payload)
 		if err != nil {
-			c.Logger.Warn("Cannot ack the message", "error", err, "transaction_id", cmd.TransactionID())
+			return fmt.Errorf("send command: %w", err)
 		}
-	case <-c.ctx.Done():
+		return nil
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- internal.Send(payload)
+	}()
+	select {
+	case err := <-sent:
+		if err != nil {
+			return fmt.Errorf("send command: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-func (c *Client) unstoreAckWaiter(transactionID string) {
-	// Closes and delete the channel from ackWaiters.
-	ch, ok := c.ackWaiters.Get(transactionID)
+func (c *Client) endSubscription(tunnelName string, cause error) {
+	sub, ok := c.listeners.Get(tunnelName)
 	if ok {
-		close(ch)
-		c.ackWaiters.Delete(transactionID)
+		sub.end(cause)
 	}
 }
 
 func (c *Client) keepConnectedLoop() {
-	c.wg.Add(1)
 	defer c.wg.Done()
 	for {
 		select {
@@ -282,49 +812,159 @@ func (c *Client) keepConnectedLoop() {
 			return
 		case <-c.internal.Done():
 			c.Logger.Debug("Connection lost with Tunnel server")
+			if c.hooks.OnDisconnected != nil {
+				c.hooks.OnDisconnected()
+			}
+			c.setState(StateDisconnected, 0, nil)
 			c.resetInternal()
-			hasReconnect := c.retryToConnect()
-			if !hasReconnect {
+			err := c.retryToConnect()
+			if errors.Is(err, ErrReconnectGaveUp) {
+				c.Logger.Error("Gave up reconnecting to Tunnel server", "error", err)
+				c.cancel(err)
+				c.setState(StateStopped, 0, err)
+				return
+			}
+			if err != nil {
 				c.Logger.Debug("Client asked to stop. Not reconnected")
 				return
 			}
 			c.Logger.Debug("Reconnected !")
+			c.startHeartbeat()
+			c.restore()
+			c.connected()
 		}
 	}
 }
 
-func (c *Client) retryToConnect() bool {
-	// retries to connect to the configured Tunnel server.
-	// Returns true if it succeeds reconnect, false otherwise.
-	// The delay between retries is incrementing by 20%.
-	// After 30 tries, the delay is around 3m17s and the time spend retrying is around 16m24s.
-	// TODO: Implement a max retries
-	// TODO: Introduce retry policy to allow the user to choose
-	delay := time.Second
+func (c *Client) retryToConnect() error {
+	// retries to connect to the configured Tunnel server, the delays between the attempts following the RetryPolicy.
+	// Returns nil if it succeeds reconnect, ErrReconnectGaveUp when the RetryPolicy gives up,
+	// or the Client's stop cause when it is stopped meanwhile.
 	c.Logger.Debug("Retry to connect...")
-	err := c.internal.Connect()
-	for err != nil {
+	c.setState(StateReconnecting, 1, nil)
+	err := c.connect()
+	for attempt := 1; err != nil; attempt++ {
+		delay, retry := c.retryPolicy.Delay(attempt)
+		if !retry {
+			return fmt.Errorf("%w after %d attempts: %w", ErrReconnectGaveUp, attempt, err)
+		}
 		c.Logger.Debug("Cannot reach Tunnel server. Retrying after delay", "delay", delay)
 		select {
 		case <-c.ctx.Done():
-			return false
+			return context.Cause(c.ctx)
 		case <-time.After(delay):
-			delay = time.Duration(float64(delay) * 1.2).Round(time.Millisecond)
-			err = c.internal.Connect()
+			c.setState(StateReconnecting, attempt+1, err)
+			err = c.connect()
 		}
 	}
-	return true
+	return nil
+}
+
+func (c *Client) connect() error {
+	// Connects the internal client and performs the handshake.
+	err := c.internal.Connect()
+	if err != nil {
+		return err
+	}
+	err = c.handshake()
+	if err != nil {
+		c.internal.Stop()
+		c.resetInternal() // A stopped internal client cannot be reused
+		return err
+	}
+	return nil
 }
 
 func (c *Client) resetInternal() {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
-	c.internal = newTCPClient(&tcp.ClientOption{
-		Addr:      c.addr,
-		OnPayload: c.onPayload,
+	c.internal = c.newInternal()
+	c.ready = make(chan struct{})
+	c.capabilities = Capabilities{}
+	c.framing = pdu.DelimitedFraming
+	c.compression = pdu.NoCompression
+}
+
+func (c *Client) waitReady(ctx context.Context) error {
+	// Returns nil once the Client is connected (see connected). While reconnecting, fails with ErrNotConnected
+	// or waits for the Client to be connected again (see WithWaitForReconnect).
+	for {
+		if c.ctx.Err() != nil {
+			return c.Err()
+		}
+		c.mtx.Lock()
+		ready := c.ready
+		c.mtx.Unlock()
+		select {
+		case <-ready:
+			return nil
+		default:
+		}
+		if !c.waitForReconnect {
+			return ErrNotConnected
+		}
+
+		// Waits for the handshake and the restoration to be done, not only the connection.
+		select {
+		case <-c.connectedSignal():
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.ctx.Done():
+			return c.Err()
+		}
+	}
+}
+
+func (c *Client) cancel(cause error) {
+	// Stops the Client's routines. Guarded by mtx so no routine is spawned once Stop waits for them (see spawn).
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stopFn(cause)
+}
+
+func (c *Client) spawn(fn func()) bool {
+	// Runs fn in a routine awaited by Stop. Returns false, without running it, if the Client is stopped.
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.ctx.Err() != nil {
+		return false
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+func (c *Client) connectionLost() <-chan struct{} {
+	// Returns a channel closed when the current connection is lost.
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.internal.Done()
+}
+
+func (c *Client) newInternal() TCPClient {
+	return newTCPClient(&tcp.ClientOption{
+		Addr:         c.addr,
+		Dialer:       c.dialer,
+		OnPayload:    c.onPayload,
+		ReadTimeout:  c.readTimeout,
+		MaxFrameSize: maxFrameSize,
 	})
 }
 
+func (c *Client) connected() {
+	// Invoked once the handshake (and the restoration after a reconnection) is done, the operations can be sent.
+	c.mtx.Lock()
+	close(c.ready)
+	c.mtx.Unlock()
+	c.setState(StateConnected, 0, nil)
+	if c.hooks.OnConnected != nil {
+		c.hooks.OnConnected()
+	}
+}
+
 var newTCPClient = func(opts *tcp.ClientOption) TCPClient {
 	return tcp.NewClient(opts)
 }
@@ -14,7 +14,6 @@ import (
 	"github.com/codingLayce/tunnel.go/id"
 	"github.com/codingLayce/tunnel.go/pdu"
 	"github.com/codingLayce/tunnel.go/pdu/command"
-	"github.com/codingLayce/tunnel.go/test-helper/mock"
 )
 
 func TestConnect(t *testing.T) {
@@ -31,7 +30,6 @@ func TestConnect_ServerDisconnecting(t *testing.T) {
 	connectCalled := make(chan error)
 	tcpClient := newTestTCPClient()
 	tcpClient.connect = func() error {
-		tcpClient.done = make(chan struct{})
 		return <-connectCalled // Blocks until it gets the error to return and returns it.
 	}
 	mockNewTCPClient(t, tcpClient)
@@ -56,7 +54,7 @@ func TestConnect_ServerDisconnecting(t *testing.T) {
 	}
 
 	// Stop the internal connection
-	close(tcpClient.done)
+	tcpClient.disconnect()
 
 	// Wait for the automatic connection retry and makes it fail
 	select {
@@ -90,7 +88,6 @@ func TestClient_StoppedWhileRetryConnect(t *testing.T) {
 	tcpClient := newTestTCPClient()
 	connectCalled := atomic.Int32{}
 	tcpClient.connect = func() error {
-		tcpClient.done = make(chan struct{})
 		connectCalled.Add(1)
 		if connectCalled.Load() == 1 {
 			return nil
@@ -103,7 +100,7 @@ func TestClient_StoppedWhileRetryConnect(t *testing.T) {
 	require.NoError(t, err)
 
 	time.Sleep(50 * time.Millisecond)
-	close(tcpClient.done)
+	tcpClient.disconnect()
 	time.Sleep(50 * time.Millisecond)
 
 	stopped := make(chan struct{})
@@ -211,9 +208,28 @@ func TestClient_CreateBTunnel_NackError(t *testing.T) {
 	assert.EqualError(t, err, "server nack")
 }
 
-func TestClient_CreateBTunnel_TimeoutError_NoResponse(t *testing.T) {
-	mock.Do(t, &waitForAckTimeout, time.Second) // Not too long for tests execution
+func TestClient_CreateQTunnel(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.QueueTunnelCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		createTunnel, ok := cmd.(*command.CreateTunnel)
+		if assert.True(t, ok, "Server should have received a CreateTunnel command") {
+			assert.Equal(t, "MyTunnel", createTunnel.Name)
+			assert.Equal(t, command.QueueTunnel, createTunnel.Type)
+		}
+		return ack(cmd)
+	})
 
+	err = cl.CreateQTunnel("MyTunnel")
+	require.NoError(t, err)
+}
+
+func TestClient_CreateQTunnel_UnsupportedFeature(t *testing.T) {
 	tcpClient := newTestTCPClient()
 	mockNewTCPClient(t, tcpClient)
 
@@ -221,6 +237,19 @@ func TestClient_CreateBTunnel_TimeoutError_NoResponse(t *testing.T) {
 	require.NoError(t, err)
 	defer cl.Stop()
 
+	err = cl.CreateQTunnel("MyTunnel")
+	assert.ErrorIs(t, err, ErrUnsupportedFeature)
+	assert.EqualError(t, err, "feature not supported by the server: queue_tunnel")
+}
+
+func TestClient_CreateBTunnel_TimeoutError_NoResponse(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("", WithAckTimeout(time.Second)) // Not too long for tests execution
+	require.NoError(t, err)
+	defer cl.Stop()
+
 	go func() {
 		select {
 		case cmd := <-tcpClient.commandsChan():
@@ -235,16 +264,14 @@ func TestClient_CreateBTunnel_TimeoutError_NoResponse(t *testing.T) {
 	}()
 
 	err = cl.CreateBTunnel("MyTunnel")
-	assert.EqualError(t, err, "timeout waiting for server acknowledgement")
+	assert.ErrorIs(t, err, ErrTimeout)
 }
 
 func TestClient_CreateBTunnel_TimeoutError_WrongAckTransactionID(t *testing.T) {
-	mock.Do(t, &waitForAckTimeout, time.Second) // Not too long for tests execution
-
 	tcpClient := newTestTCPClient()
 	mockNewTCPClient(t, tcpClient)
 
-	cl, err := Connect("")
+	cl, err := Connect("", WithAckTimeout(time.Second)) // Not too long for tests execution
 	require.NoError(t, err)
 	defer cl.Stop()
 
@@ -268,12 +295,10 @@ func TestClient_CreateBTunnel_TimeoutError_WrongAckTransactionID(t *testing.T) {
 }
 
 func TestClient_CreateBTunnel_TimeoutError_InvalidPayload(t *testing.T) {
-	mock.Do(t, &waitForAckTimeout, time.Second) // Not too long for tests execution
-
 	tcpClient := newTestTCPClient()
 	mockNewTCPClient(t, tcpClient)
 
-	cl, err := Connect("")
+	cl, err := Connect("", WithAckTimeout(time.Second)) // Not too long for tests execution
 	require.NoError(t, err)
 	defer cl.Stop()
 
@@ -296,12 +321,10 @@ func TestClient_CreateBTunnel_TimeoutError_InvalidPayload(t *testing.T) {
 }
 
 func TestClient_CreateBTunnel_TimeoutError_UnsupportedCommand(t *testing.T) {
-	mock.Do(t, &waitForAckTimeout, time.Second) // Not too long for tests execution
-
 	tcpClient := newTestTCPClient()
 	mockNewTCPClient(t, tcpClient)
 
-	cl, err := Connect("")
+	cl, err := Connect("", WithAckTimeout(time.Second)) // Not too long for tests execution
 	require.NoError(t, err)
 	defer cl.Stop()
 
@@ -323,6 +346,66 @@ func TestClient_CreateBTunnel_TimeoutError_UnsupportedCommand(t *testing.T) {
 	assert.EqualError(t, err, "timeout waiting for server acknowledgement")
 }
 
+func TestClient_DeleteTunnel(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("MyTunnel", func(_ string) {})
+	require.NoError(t, err)
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			slog.Debug("[SERVER] Received command", "command", cmd.Info())
+			deleteTunnel, ok := cmd.(*command.DeleteTunnel)
+			require.True(t, ok)
+			assert.Equal(t, "MyTunnel", deleteTunnel.Name)
+			tcpClient.callOnPayload(pdu.Marshal(command.NewAckWithTransactionID(cmd.TransactionID())))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received a DeleteTunnel command")
+		}
+	}()
+
+	err = cl.DeleteTunnel("MyTunnel")
+	require.NoError(t, err)
+	assert.ErrorIs(t, sub.Err(), ErrTunnelDeleted)
+}
+
+func TestClient_DeleteTunnel_ValidationError(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	err = cl.DeleteTunnel("Un super tunnel avec un mauvais _nom")
+	assert.EqualError(t, err, "validate command: invalid name")
+}
+
+func TestClient_DeleteTunnel_NackError(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		nack := command.NewNackWithTransactionID(cmd.TransactionID())
+		nack.Code = command.NackTunnelNotFound
+		return nack
+	})
+
+	err = cl.DeleteTunnel("MyTunnel")
+	assert.ErrorIs(t, err, ErrTunnelNotFound)
+}
+
 func TestClient_ListenTunnel(t *testing.T) {
 	tcpClient := newTestTCPClient()
 	mockNewTCPClient(t, tcpClient)
@@ -344,14 +427,14 @@ func TestClient_ListenTunnel(t *testing.T) {
 
 			time.Sleep(50 * time.Millisecond) // Let time to listener to be created
 			// send message
-			tcpClient.callOnPayload(pdu.Marshal(command.NewReceiveMessage(listenTunnel.Name, "This is a message")))
+			tcpClient.callOnPayload(pdu.Marshal(command.NewReceiveMessage(listenTunnel.Name, []byte("This is a message"))))
 		case <-time.After(50 * time.Millisecond):
 			assert.FailNow(t, "Server should have received a CreateTunnel command")
 		}
 	}()
 
 	receivedMsg := make(chan string)
-	err = cl.ListenTunnel("Bidule", func(msg string) {
+	_, err = cl.ListenTunnel("Bidule", func(msg string) {
 		receivedMsg <- msg
 	})
 	require.NoError(t, err)
@@ -380,7 +463,7 @@ func TestClient_ListenTunnel_ValidationError(t *testing.T) {
 	require.NoError(t, err)
 	defer cl.Stop()
 
-	err = cl.ListenTunnel("Un super tunnel avec un mauvais _nom", func(_ string) {})
+	_, err = cl.ListenTunnel("Un super tunnel avec un mauvais _nom", func(_ string) {})
 	assert.EqualError(t, err, "validate command: invalid name")
 }
 
@@ -395,7 +478,7 @@ func TestClient_ListenTunnel_SendError(t *testing.T) {
 	require.NoError(t, err)
 	defer cl.Stop()
 
-	err = cl.ListenTunnel("MonTunnel", func(_ string) {})
+	_, err = cl.ListenTunnel("MonTunnel", func(_ string) {})
 	assert.EqualError(t, err, "send command: error")
 }
 
@@ -422,7 +505,7 @@ func TestClient_ListenTunnel_NackError(t *testing.T) {
 		}
 	}()
 
-	err = cl.ListenTunnel("MyTunnel", func(_ string) {})
+	_, err = cl.ListenTunnel("MyTunnel", func(_ string) {})
 	assert.EqualError(t, err, "server nack")
 }
 
@@ -441,7 +524,7 @@ func TestClient_PublishMessage(t *testing.T) {
 			publishMessage, ok := cmd.(*command.PublishMessage)
 			require.True(t, ok)
 			assert.Equal(t, "Bidule", publishMessage.TunnelName)
-			assert.Equal(t, "Mon super message", publishMessage.Message)
+			assert.Equal(t, []byte("Mon super message"), publishMessage.Message)
 			// send ack
 			time.Sleep(50 * time.Millisecond) // Let time to waiter to be created
 			tcpClient.callOnPayload(pdu.Marshal(command.NewAckWithTransactionID(cmd.TransactionID())))
@@ -496,7 +579,7 @@ func TestClient_PublishMessage_NackError(t *testing.T) {
 			publishMessage, ok := cmd.(*command.PublishMessage)
 			require.True(t, ok)
 			assert.Equal(t, "MyTunnel", publishMessage.TunnelName)
-			assert.Equal(t, "Mon message", publishMessage.Message)
+			assert.Equal(t, []byte("Mon message"), publishMessage.Message)
 			// send nack
 			time.Sleep(50 * time.Millisecond) // Let time to waiter to be created
 			tcpClient.callOnPayload(pdu.Marshal(command.NewNackWithTransactionID(cmd.TransactionID())))
@@ -508,3 +591,347 @@ func TestClient_PublishMessage_NackError(t *testing.T) {
 	err = cl.PublishMessage("MyTunnel", "Mon message")
 	assert.EqualError(t, err, "server nack")
 }
+
+func TestClient_PublishBytes(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	message := []byte{'{', '"', 0xc3, 0xa9, '"', ':', ' ', 0x00, 0xff, '}'}
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			slog.Debug("[SERVER] Received command", "command", cmd.Info())
+			publishMessage, ok := cmd.(*command.PublishMessage)
+			require.True(t, ok)
+			assert.Equal(t, "Bidule", publishMessage.TunnelName)
+			assert.Equal(t, message, publishMessage.Message)
+			// send ack
+			time.Sleep(50 * time.Millisecond) // Let time to waiter to be created
+			tcpClient.callOnPayload(pdu.Marshal(command.NewAckWithTransactionID(cmd.TransactionID())))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received a PublishMessage command")
+		}
+	}()
+
+	err = cl.PublishBytes("Bidule", message)
+	require.NoError(t, err)
+}
+
+func TestClient_PublishBytes_DelimiterError(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	err = cl.PublishBytes("Bidule", []byte("Multi\nline"))
+	assert.EqualError(t, err, `frame command: data contains the delimiter '\n', length_prefixed framing is required`)
+}
+
+func TestClient_ListenTunnelBytes(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	message := []byte{'{', '"', 0xc3, 0xa9, '"', ':', ' ', 0x00, 0xff, '}'}
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			slog.Debug("[SERVER] Received command", "command", cmd.Info())
+			listenTunnel, ok := cmd.(*command.ListenTunnel)
+			require.True(t, ok)
+			// send ack
+			time.Sleep(50 * time.Millisecond) // Let time to waiter to be created
+			tcpClient.callOnPayload(pdu.Marshal(command.NewAckWithTransactionID(cmd.TransactionID())))
+
+			time.Sleep(50 * time.Millisecond) // Let time to listener to be created
+			// send message
+			tcpClient.callOnPayload(pdu.Marshal(command.NewReceiveMessage(listenTunnel.Name, message)))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received a ListenTunnel command")
+		}
+	}()
+
+	receivedMsg := make(chan []byte)
+	_, err = cl.ListenTunnelBytes("Bidule", func(msg []byte) {
+		receivedMsg <- msg
+	})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-receivedMsg:
+		assert.Equal(t, message, msg)
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A message should have been received")
+	}
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		_, isAck := cmd.(*command.Ack)
+		assert.True(t, isAck, "Command should have been ack")
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A ack should have been received server side")
+	}
+}
+
+func TestClient_CreateBTunnel_TypedNackError(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			slog.Debug("[SERVER] Received command", "command", cmd.Info())
+			nack := command.NewNackWithTransactionID(cmd.TransactionID())
+			nack.Code = command.NackTunnelExists
+			nack.Reason = "MyTunnel already exists"
+			tcpClient.callOnPayload(pdu.Marshal(nack))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received a CreateTunnel command")
+		}
+	}()
+
+	err = cl.CreateBTunnel("MyTunnel")
+	assert.EqualError(t, err, "server nack (tunnel_exists): MyTunnel already exists")
+	assert.ErrorIs(t, err, ErrNack)
+	assert.ErrorIs(t, err, ErrTunnelExists)
+}
+
+func TestClient_PublishMessage_NotConnected(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	connectCalled := atomic.Int32{}
+	tcpClient.connect = func() error {
+		if connectCalled.Add(1) == 1 {
+			return nil
+		}
+		tcpClient.disconnect() // Stays disconnected
+		return errors.New("error")
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	tcpClient.disconnect()
+	// Wait for the failed reconnection, next one is 1s later
+	assert.Eventually(t, func() bool {
+		return connectCalled.Load() == 2
+	}, 50*time.Millisecond, 5*time.Millisecond)
+
+	err = cl.PublishMessage("MyTunnel", "Mon message")
+	assert.EqualError(t, err, "send command: not connected to Tunnel server")
+	assert.ErrorIs(t, err, ErrNotConnected)
+}
+
+func TestClient_PublishWithHeaders(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	headers := map[string]string{"content-type": "application/json", "correlation-id": "1234"}
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			slog.Debug("[SERVER] Received command", "command", cmd.Info())
+			publishMessage, ok := cmd.(*command.PublishMessage)
+			require.True(t, ok)
+			assert.Equal(t, "Bidule", publishMessage.TunnelName)
+			assert.Equal(t, command.Headers(headers), publishMessage.Headers)
+			assert.Equal(t, []byte(`{"key": "value"}`), publishMessage.Message)
+			tcpClient.callOnPayload(pdu.Marshal(command.NewAckWithTransactionID(cmd.TransactionID())))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received a PublishMessage command")
+		}
+	}()
+
+	err = cl.PublishWithHeaders("Bidule", []byte(`{"key": "value"}`), headers)
+	require.NoError(t, err)
+}
+
+func TestClient_PublishWithHeaders_UnsupportedFeature(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	err = cl.PublishWithHeaders("Bidule", []byte("message"), map[string]string{"trace-id": "abcd"})
+	assert.EqualError(t, err, "feature not supported by the server: headers")
+	assert.ErrorIs(t, err, ErrUnsupportedFeature)
+}
+
+func TestClient_ListenTunnelMessages(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			slog.Debug("[SERVER] Received command", "command", cmd.Info())
+			listenTunnel, ok := cmd.(*command.ListenTunnel)
+			require.True(t, ok)
+			// send ack
+			tcpClient.callOnPayload(pdu.Marshal(command.NewAckWithTransactionID(cmd.TransactionID())))
+
+			time.Sleep(50 * time.Millisecond) // Let time to listener to be created
+			// send message
+			receiveMessage := command.NewReceiveMessage(listenTunnel.Name, []byte("This is a message"))
+			receiveMessage.Headers = command.Headers{"trace-id": "abcd"}
+			tcpClient.callOnPayload(pdu.Marshal(receiveMessage))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received a ListenTunnel command")
+		}
+	}()
+
+	receivedMsg := make(chan *Message)
+	_, err = cl.ListenTunnelMessages("Bidule", func(msg *Message) {
+		receivedMsg <- msg
+	})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-receivedMsg:
+		assert.Equal(t, &Message{
+			TunnelName: "Bidule",
+			Headers:    map[string]string{"trace-id": "abcd"},
+			Body:       []byte("This is a message"),
+		}, msg)
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A message should have been received")
+	}
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		_, isAck := cmd.(*command.Ack)
+		assert.True(t, isAck, "Command should have been ack")
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A ack should have been received server side")
+	}
+}
+
+func TestClient_Publish(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.MessageIDCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	published := make(chan *command.PublishMessage, 1)
+	serve := func(cmd command.Command) command.Command {
+		publishMessage, ok := cmd.(*command.PublishMessage)
+		if assert.True(t, ok, "Server should have received a PublishMessage command") {
+			published <- publishMessage
+		}
+		return ack(cmd)
+	}
+
+	// Generated ID
+	replyNext(t, tcpClient, serve)
+	msg := &Message{TunnelName: "Bidule", Headers: map[string]string{"trace-id": "abcd"}, Body: []byte("This is a message")}
+	require.NoError(t, cl.Publish(msg))
+	assert.NotEmpty(t, msg.ID)
+	publishMessage := <-published
+	assert.Equal(t, msg.ID, publishMessage.MessageID)
+	assert.Equal(t, command.Headers{"trace-id": "abcd"}, publishMessage.Headers)
+	assert.Equal(t, []byte("This is a message"), publishMessage.Message)
+
+	// Each message has its own ID
+	replyNext(t, tcpClient, serve)
+	require.NoError(t, cl.PublishMessage("Bidule", "This is a message"))
+	assert.NotEqual(t, msg.ID, (<-published).MessageID)
+
+	// Given ID
+	replyNext(t, tcpClient, serve)
+	require.NoError(t, cl.Publish(&Message{TunnelName: "Bidule", ID: "my-message-1", Body: []byte("This is a message")}))
+	assert.Equal(t, "my-message-1", (<-published).MessageID)
+}
+
+func TestClient_Publish_ValidationError(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.MessageIDCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	err = cl.Publish(&Message{TunnelName: "Bidule", ID: "my message", Body: []byte("This is a message")})
+	assert.EqualError(t, err, "validate command: invalid message_id")
+}
+
+func TestClient_Publish_MessageIDUnsupported(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	// Servers not supporting the message ids reject the tunnel names with a ':', no ID is sent.
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		publishMessage, ok := cmd.(*command.PublishMessage)
+		if assert.True(t, ok, "Server should have received a PublishMessage command") {
+			assert.Empty(t, publishMessage.MessageID)
+		}
+		return ack(cmd)
+	})
+	msg := &Message{TunnelName: "Bidule", Body: []byte("This is a message")}
+	require.NoError(t, cl.Publish(msg))
+	assert.Empty(t, msg.ID)
+
+	err = cl.Publish(&Message{TunnelName: "Bidule", ID: "my-message-1", Body: []byte("This is a message")})
+	assert.EqualError(t, err, "feature not supported by the server: message_id")
+	assert.ErrorIs(t, err, ErrUnsupportedFeature)
+}
+
+func TestClient_ListenTunnelMessages_MessageID(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	received := make(chan *Message, 1)
+	_, err = cl.ListenTunnelMessages("Bidule", func(msg *Message) {
+		received <- msg
+	})
+	require.NoError(t, err)
+
+	receiveMessage := command.NewReceiveMessage("Bidule", []byte("This is a message"))
+	receiveMessage.MessageID = "9m4e2mr0ui3e8a215n4g"
+	go tcpClient.callOnPayload(pdu.Marshal(receiveMessage))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "9m4e2mr0ui3e8a215n4g", msg.ID)
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A message should have been received")
+	}
+	<-tcpClient.commandsChan() // ack
+}
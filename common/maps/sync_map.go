@@ -21,6 +21,18 @@ func (s *SyncMap[Key, Value]) Put(key Key, value Value) {
 	s.internalMap[key] = value
 }
 
+// PutIfAbsent stores the value only if the key is not already stored.
+// Returns true if the value has been stored.
+func (s *SyncMap[Key, Value]) PutIfAbsent(key Key, value Value) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, exists := s.internalMap[key]; exists {
+		return false
+	}
+	s.internalMap[key] = value
+	return true
+}
+
 func (s *SyncMap[Key, Value]) Has(key Key) bool {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
@@ -47,6 +59,19 @@ func (s *SyncMap[Key, Value]) Delete(key Key) {
 	delete(s.internalMap, key)
 }
 
+// DeleteIf deletes the key only if its value satisfies the predicate.
+// Returns true if the key has been deleted.
+func (s *SyncMap[Key, Value]) DeleteIf(key Key, predicate func(value Value) bool) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	value, exists := s.internalMap[key]
+	if !exists || !predicate(value) {
+		return false
+	}
+	delete(s.internalMap, key)
+	return true
+}
+
 func (s *SyncMap[Key, Value]) Foreach(f func(key Key, value Value)) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
@@ -3,6 +3,7 @@ package maps
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -61,3 +62,38 @@ func TestSyncMap_Foreach(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestSyncMap_PutIfAbsent(t *testing.T) {
+	m := NewSyncMap[int, string]()
+
+	concurrentProcesses := 100
+	stored := atomic.Int32{}
+	wg := sync.WaitGroup{}
+	wg.Add(concurrentProcesses)
+	for i := 0; i < concurrentProcesses; i++ {
+		go func() {
+			defer wg.Done()
+			if m.PutIfAbsent(17, fmt.Sprintf("Value %d", i)) {
+				stored.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), stored.Load())
+	assert.Equal(t, 1, m.Len())
+}
+
+func TestSyncMap_DeleteIf(t *testing.T) {
+	m := NewSyncMap[int, string]()
+	m.Put(1, "Value 1")
+
+	isValue2 := func(value string) bool { return value == "Value 2" }
+	assert.False(t, m.DeleteIf(1, isValue2))
+	assert.False(t, m.DeleteIf(2, isValue2))
+	assert.True(t, m.Has(1))
+
+	m.Put(1, "Value 2")
+	assert.True(t, m.DeleteIf(1, isValue2))
+	assert.False(t, m.Has(1))
+}
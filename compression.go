@@ -0,0 +1,15 @@
+package tunnel
+
+// defaultCompressionThreshold is the minimum data size (bytes) of the compressed commands,
+// the smaller ones are not worth it.
+const defaultCompressionThreshold = 1024
+
+// SetCompressionThreshold changes the minimum data size (bytes) of the commands compressed by the Client.
+// A command is only compressed when it is smaller once compressed. 0 disables the compression.
+// The commands are compressed only when the server supports it (see Capabilities), the compressed commands sent by the
+// server are always decompressed.
+func (c *Client) SetCompressionThreshold(threshold int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.compressionThreshold = threshold
+}
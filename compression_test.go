@@ -0,0 +1,149 @@
+package tunnel
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestClient_Compression_Publish(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		capability command.Capability
+	}{
+		{name: "Deflate", capability: command.DeflateCompressionCapability},
+		{name: "Gzip", capability: command.GzipCompressionCapability},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tcpClient := newTestTCPClientWithCapabilities(command.LengthPrefixedFramingCapability, tc.capability)
+			mockNewTCPClient(t, tcpClient)
+
+			cl, err := Connect("")
+			require.NoError(t, err)
+			defer cl.Stop()
+
+			payloads := make(chan []byte, 1)
+			tcpClient.send = func(payload []byte) error {
+				payloads <- payload
+				cmd, err := tcpClient.framing.UnmarshalCompressed(payload, tcpClient.compression, 0)
+				require.NoError(t, err)
+				go tcpClient.callOnPayload(tcpClient.framing.Marshal(ack(cmd)))
+				return nil
+			}
+
+			body := bytes.Repeat([]byte("compressible "), 200)
+			err = cl.PublishBytes("Bidule", body)
+			require.NoError(t, err)
+
+			payload := <-payloads
+			assert.NotZero(t, payload[0]&pdu.CompressedFlag, "The large command should have been compressed")
+			assert.Less(t, len(payload), len(body))
+			cmd, err := tcpClient.framing.UnmarshalCompressed(payload, tcpClient.compression, 0)
+			require.NoError(t, err)
+			publishMessage, ok := cmd.(*command.PublishMessage)
+			require.True(t, ok)
+			assert.Equal(t, body, publishMessage.Message)
+
+			err = cl.PublishMessage("Bidule", "small")
+			require.NoError(t, err)
+
+			payload = <-payloads
+			assert.Zero(t, payload[0]&pdu.CompressedFlag, "The small command shouldn't have been compressed")
+		})
+	}
+}
+
+func TestClient_Compression_Disabled(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.LengthPrefixedFramingCapability, command.DeflateCompressionCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+	cl.SetCompressionThreshold(0)
+
+	payloads := make(chan []byte, 1)
+	tcpClient.send = func(payload []byte) error {
+		payloads <- payload
+		cmd, err := tcpClient.framing.Unmarshal(payload)
+		require.NoError(t, err)
+		go tcpClient.callOnPayload(tcpClient.framing.Marshal(ack(cmd)))
+		return nil
+	}
+
+	err = cl.PublishBytes("Bidule", bytes.Repeat([]byte("compressible "), 200))
+	require.NoError(t, err)
+
+	payload := <-payloads
+	assert.Zero(t, payload[0]&pdu.CompressedFlag, "The compression should have been disabled")
+}
+
+func TestClient_Compression_NotAgreed(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.LengthPrefixedFramingCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	payloads := make(chan []byte, 1)
+	tcpClient.send = func(payload []byte) error {
+		payloads <- payload
+		cmd, err := tcpClient.framing.Unmarshal(payload)
+		require.NoError(t, err)
+		go tcpClient.callOnPayload(tcpClient.framing.Marshal(ack(cmd)))
+		return nil
+	}
+
+	err = cl.PublishBytes("Bidule", bytes.Repeat([]byte("compressible "), 200))
+	require.NoError(t, err)
+
+	payload := <-payloads
+	assert.Zero(t, payload[0]&pdu.CompressedFlag, "The compression hasn't been agreed")
+}
+
+func TestClient_Compression_Receive(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.LengthPrefixedFramingCapability, command.GzipCompressionCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	go func() {
+		cmd := <-tcpClient.commandsChan()
+		tcpClient.callOnPayload(tcpClient.framing.Marshal(ack(cmd)))
+	}()
+	received := make(chan *Message, 1)
+	_, err = cl.ListenTunnelMessages("Bidule", func(msg *Message) {
+		received <- msg
+	})
+	require.NoError(t, err)
+
+	body := bytes.Repeat([]byte("compressible "), 200)
+	payload, err := tcpClient.framing.MarshalCompressed(command.NewReceiveMessage("Bidule", body), tcpClient.compression, 0)
+	require.NoError(t, err)
+	require.NotZero(t, payload[0]&pdu.CompressedFlag)
+	go tcpClient.callOnPayload(payload)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, body, msg.Body)
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A message should have been received")
+	}
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		_, isAck := cmd.(*command.Ack)
+		assert.True(t, isAck, "Command should have been ack")
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A ack should have been received server side")
+	}
+}
@@ -0,0 +1,694 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// silence is how long a server must stay silent when no command is expected.
+const silence = 200 * time.Millisecond
+
+// unknownIndicator is not assigned by the protocol.
+const unknownIndicator byte = '$'
+
+// Checks returns the suite checking the whole protocol. The checks needing a capability are skipped without it.
+func Checks() []Check {
+	return []Check{
+		{Name: "handshake", Run: checkHandshake},
+		{Name: "create_tunnel", Run: checkCreateTunnel},
+		{Name: "create_existing_tunnel", Run: checkCreateExistingTunnel},
+		{Name: "listen_unknown_tunnel", Run: checkListenUnknownTunnel},
+		{Name: "broadcast_tunnel", Run: checkBroadcastTunnel},
+		{Name: "queue_tunnel", Run: checkQueueTunnel},
+		{Name: "topic_tunnel", Run: checkTopicTunnel},
+		{Name: "open_publish", Run: checkOpenPublish},
+		{Name: "unlisten_tunnel", Run: checkUnlistenTunnel},
+		{Name: "delete_tunnel", Run: checkDeleteTunnel},
+		{Name: "list_and_describe_tunnels", Run: checkListAndDescribeTunnels},
+		{Name: "heartbeat", Run: checkHeartbeat},
+		{Name: "ack_ordering", Run: checkAckOrdering},
+		{Name: "unknown_indicator", Run: checkUnknownIndicator},
+		{Name: "malformed_command", Run: checkMalformedCommand},
+		{Name: "malformed_frame", Run: checkMalformedFrame},
+		{Name: "max_frame_size", Run: checkMaxFrameSize},
+	}
+}
+
+// uniqueName returns a Tunnel name that cannot collide with the existing ones, nor the ones of other runs.
+func uniqueName(prefix string) string {
+	return prefix + "-" + xid.New().String()
+}
+
+func expectAck(session *Session, cmd command.Command) error {
+	response, err := session.Request(cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.Info(), err)
+	}
+	if _, ok := response.(*command.Ack); !ok {
+		return fmt.Errorf("%s: expected ACK, got %s", cmd.Info(), response.Info())
+	}
+	return nil
+}
+
+// expectNack checks that the command is nacked, with one of the codes or without code.
+func expectNack(session *Session, cmd command.Command, codes ...command.NackCode) error {
+	response, err := session.Request(cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.Info(), err)
+	}
+	return checkNack(cmd.Info(), response, codes...)
+}
+
+func checkNack(info string, response command.Command, codes ...command.NackCode) error {
+	nack, ok := response.(*command.Nack)
+	if !ok {
+		return fmt.Errorf("%s: expected NACK, got %s", info, response.Info())
+	}
+	if nack.Code != command.NackUnknown && !slices.Contains(codes, nack.Code) {
+		return fmt.Errorf("%s: expected NACK with code %v, got %s", info, codes, nack.Code)
+	}
+	return nil
+}
+
+// createTunnel creates the Tunnel, deleted once the Check is done.
+func createTunnel(session *Session, name string, tunnelType command.TunnelType) error {
+	session.target.DeleteAfter(name)
+	cmd := command.NewCreateTunnel(name)
+	cmd.Type = tunnelType
+	return expectAck(session, cmd)
+}
+
+// expectMessage waits for a message of the Tunnel and acks it.
+func expectMessage(session *Session, tunnelName string) (*command.ReceiveMessage, error) {
+	cmd, err := session.Expect(fmt.Sprintf("message of %s", tunnelName), func(cmd command.Command) bool {
+		message, ok := cmd.(*command.ReceiveMessage)
+		return ok && message.TunnelName == tunnelName
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = session.Send(command.NewAckWithTransactionID(cmd.TransactionID()))
+	if err != nil {
+		return nil, err
+	}
+	return cmd.(*command.ReceiveMessage), nil
+}
+
+// connect opens the sessions, skipping the Check when a capability hasn't been agreed.
+func connect(target *Target, count int, capabilities ...command.Capability) ([]*Session, error) {
+	sessions := make([]*Session, count)
+	for i := range sessions {
+		session, err := target.Connect()
+		if err != nil {
+			return nil, err
+		}
+		for _, capability := range capabilities {
+			if !session.Has(capability) {
+				return nil, fmt.Errorf("%w: %s capability not agreed", ErrSkipped, capability)
+			}
+		}
+		sessions[i] = session
+	}
+	return sessions, nil
+}
+
+func checkHandshake(target *Target) error {
+	session, err := target.Dial()
+	if err != nil {
+		return err
+	}
+
+	offer := command.NewHello()
+	offer.MaxFrameSize = maxFrameSize
+	offer.Capabilities = target.opts.Capabilities
+	response, err := session.Request(offer)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := response.(*command.Nack); ok {
+		return fmt.Errorf("%w: the server doesn't support the handshake (legacy protocol)", ErrSkipped)
+	}
+	hello, ok := response.(*command.Hello)
+	if !ok {
+		return fmt.Errorf("expected HELLO, got %s", response.Info())
+	}
+	if hello.Version > offer.Version {
+		return fmt.Errorf("agreed version %d is greater than the offered %d", hello.Version, offer.Version)
+	}
+	if hello.MaxFrameSize == 0 || hello.MaxFrameSize > offer.MaxFrameSize {
+		return fmt.Errorf("agreed max frame size %d is not within the offered %d", hello.MaxFrameSize, offer.MaxFrameSize)
+	}
+	for _, capability := range hello.Capabilities {
+		if !offer.Has(capability) {
+			return fmt.Errorf("agreed capability %q hasn't been offered", capability)
+		}
+	}
+	session.agreed(hello)
+
+	// The connection must keep working with the agreed framing.
+	return expectNack(session, command.NewDescribeTunnel(uniqueName("conformance")), command.NackTunnelNotFound)
+}
+
+func checkCreateTunnel(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+	return createTunnel(session, uniqueName("conformance"), command.BroadcastTunnel)
+}
+
+func checkCreateExistingTunnel(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+
+	name := uniqueName("conformance")
+	err = createTunnel(session, name, command.BroadcastTunnel)
+	if err != nil {
+		return err
+	}
+	return expectNack(session, command.NewCreateTunnel(name), command.NackTunnelExists)
+}
+
+func checkListenUnknownTunnel(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+	return expectNack(session, command.NewListenTunnel(uniqueName("conformance")), command.NackTunnelNotFound)
+}
+
+func checkBroadcastTunnel(target *Target) error {
+	sessions, err := connect(target, 3)
+	if err != nil {
+		return err
+	}
+	publisher, listeners := sessions[0], sessions[1:]
+
+	name := uniqueName("conformance")
+	err = createTunnel(publisher, name, command.BroadcastTunnel)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		err = expectAck(session, command.NewListenTunnel(name))
+		if err != nil {
+			return err
+		}
+	}
+
+	publish := command.NewPublishMessage(name, []byte("Broadcast message"))
+	if publisher.Has(command.MessageIDCapability) {
+		publish.MessageID = xid.New().String()
+	}
+	if publisher.Has(command.HeadersCapability) {
+		publish.Headers = command.Headers{"content-type": "text/plain"}
+	}
+	err = expectAck(publisher, publish)
+	if err != nil {
+		return err
+	}
+
+	for i, listener := range listeners {
+		message, err := expectMessage(listener, name)
+		if err != nil {
+			return fmt.Errorf("listener %d: %w", i, err)
+		}
+		if message.MessageID != publish.MessageID || !bytes.Equal(message.Message, publish.Message) ||
+			!maps.Equal(message.Headers, publish.Headers) {
+			return fmt.Errorf("listener %d: received message %s differs from the published one", i, message.Data())
+		}
+	}
+
+	err = publisher.ExpectSilence(silence)
+	if err != nil {
+		return fmt.Errorf("the publisher must not receive its own message: %w", err)
+	}
+	return nil
+}
+
+func checkQueueTunnel(target *Target) error {
+	sessions, err := connect(target, 3, command.QueueTunnelCapability)
+	if err != nil {
+		return err
+	}
+	publisher, listeners := sessions[0], sessions[1:]
+
+	name := uniqueName("conformance")
+	err = createTunnel(publisher, name, command.QueueTunnel)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		err = expectAck(session, command.NewListenTunnel(name))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = expectAck(publisher, command.NewPublishMessage(name, []byte("Queued message")))
+	if err != nil {
+		return err
+	}
+
+	deliveries := 0
+	for _, listener := range listeners {
+		_, err = expectMessage(listener, name)
+		if err == nil {
+			deliveries++
+		}
+	}
+	if deliveries != 1 {
+		return fmt.Errorf("the message must be delivered to a single listener, delivered to %d", deliveries)
+	}
+	return nil
+}
+
+func checkTopicTunnel(target *Target) error {
+	sessions, err := connect(target, 2, command.TopicTunnelCapability)
+	if err != nil {
+		return err
+	}
+	publisher, listener := sessions[0], sessions[1]
+
+	root := "conformance." + xid.New().String()
+	name := root + ".eu.created"
+	err = createTunnel(publisher, name, command.TopicTunnel)
+	if err != nil {
+		return err
+	}
+	err = expectAck(publisher, command.NewListenTunnel(name))
+	if err != nil {
+		return err
+	}
+	// Both patterns match, the message must be received once.
+	err = expectAck(listener, command.NewListenTunnel(root+".*.created"))
+	if err != nil {
+		return err
+	}
+	err = expectAck(listener, command.NewListenTunnel(root+".>"))
+	if err != nil {
+		return err
+	}
+
+	err = expectAck(publisher, command.NewPublishMessage(name, []byte("Topic message")))
+	if err != nil {
+		return err
+	}
+	_, err = expectMessage(listener, name)
+	if err != nil {
+		return err
+	}
+	err = listener.ExpectSilence(silence)
+	if err != nil {
+		return fmt.Errorf("the message must be received once: %w", err)
+	}
+	return nil
+}
+
+func checkOpenPublish(target *Target) error {
+	sessions, err := connect(target, 2, command.OpenPublishCapability)
+	if err != nil {
+		return err
+	}
+	publisher, listener := sessions[0], sessions[1]
+
+	name := uniqueName("conformance")
+	err = createTunnel(publisher, name, command.BroadcastTunnel)
+	if err != nil {
+		return err
+	}
+	err = expectAck(listener, command.NewListenTunnel(name))
+	if err != nil {
+		return err
+	}
+
+	// The publisher isn't listening to the Tunnel.
+	err = expectAck(publisher, command.NewPublishMessage(name, []byte("Open message")))
+	if err != nil {
+		return err
+	}
+	_, err = expectMessage(listener, name)
+	return err
+}
+
+func checkUnlistenTunnel(target *Target) error {
+	sessions, err := connect(target, 2)
+	if err != nil {
+		return err
+	}
+	publisher, listener := sessions[0], sessions[1]
+
+	name := uniqueName("conformance")
+	err = createTunnel(publisher, name, command.BroadcastTunnel)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		err = expectAck(session, command.NewListenTunnel(name))
+		if err != nil {
+			return err
+		}
+	}
+	err = expectAck(listener, command.NewUnlistenTunnel(name))
+	if err != nil {
+		return err
+	}
+	err = expectNack(listener, command.NewUnlistenTunnel(name))
+	if err != nil {
+		return fmt.Errorf("unlisten twice: %w", err)
+	}
+
+	err = expectAck(publisher, command.NewPublishMessage(name, []byte("Unlistened message")))
+	if err != nil {
+		return err
+	}
+	err = listener.ExpectSilence(silence)
+	if err != nil {
+		return fmt.Errorf("no message must be received once unlistened: %w", err)
+	}
+	return nil
+}
+
+func checkDeleteTunnel(target *Target) error {
+	sessions, err := connect(target, 2)
+	if err != nil {
+		return err
+	}
+	owner, listener := sessions[0], sessions[1]
+
+	name := uniqueName("conformance")
+	err = createTunnel(owner, name, command.BroadcastTunnel)
+	if err != nil {
+		return err
+	}
+	err = expectAck(listener, command.NewListenTunnel(name))
+	if err != nil {
+		return err
+	}
+	err = expectAck(owner, command.NewDeleteTunnel(name))
+	if err != nil {
+		return err
+	}
+
+	notification, err := listener.Expect("DELETE_TUNNEL notification", func(cmd command.Command) bool {
+		deleteTunnel, ok := cmd.(*command.DeleteTunnel)
+		return ok && deleteTunnel.Name == name
+	})
+	if err != nil {
+		return err
+	}
+	err = listener.Send(command.NewAckWithTransactionID(notification.TransactionID()))
+	if err != nil {
+		return err
+	}
+
+	err = expectNack(owner, command.NewDescribeTunnel(name), command.NackTunnelNotFound)
+	if err != nil {
+		return fmt.Errorf("describe deleted tunnel: %w", err)
+	}
+	return expectNack(owner, command.NewDeleteTunnel(name), command.NackTunnelNotFound)
+}
+
+func checkListAndDescribeTunnels(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+
+	prefix := uniqueName("conformance")
+	names := []string{prefix + "-a", prefix + "-b"}
+	for _, name := range names {
+		err = createTunnel(session, name, command.BroadcastTunnel)
+		if err != nil {
+			return err
+		}
+	}
+	err = expectAck(session, command.NewListenTunnel(names[0]))
+	if err != nil {
+		return err
+	}
+
+	describe := command.NewDescribeTunnel(names[0])
+	response, err := session.Request(describe)
+	if err != nil {
+		return fmt.Errorf("%s: %w", describe.Info(), err)
+	}
+	info, ok := response.(*command.TunnelInfo)
+	if !ok {
+		return fmt.Errorf("%s: expected TUNNEL_INFO, got %s", describe.Info(), response.Info())
+	}
+	expected := command.TunnelDescription{Name: names[0], Type: command.BroadcastTunnel, Listeners: 1}
+	if len(info.Tunnels) != 1 || info.Tunnels[0] != expected {
+		return fmt.Errorf("%s: expected %s, got %s", describe.Info(), command.NewTunnelInfo(expected).Data(), info.Data())
+	}
+
+	list := command.NewListTunnels(prefix + "-*")
+	response, err = session.Request(list)
+	if err != nil {
+		return fmt.Errorf("%s: %w", list.Info(), err)
+	}
+	info, ok = response.(*command.TunnelInfo)
+	if !ok {
+		return fmt.Errorf("%s: expected TUNNEL_INFO, got %s", list.Info(), response.Info())
+	}
+	var listed []string
+	for _, tunnel := range info.Tunnels {
+		listed = append(listed, tunnel.Name)
+	}
+	slices.Sort(listed)
+	if !slices.Equal(listed, names) {
+		return fmt.Errorf("%s: expected %v, got %v", list.Info(), names, listed)
+	}
+	return nil
+}
+
+func checkHeartbeat(target *Target) error {
+	sessions, err := connect(target, 1, command.HeartbeatCapability)
+	if err != nil {
+		return err
+	}
+
+	ping := command.NewPing()
+	response, err := sessions[0].Request(ping)
+	if err != nil {
+		return fmt.Errorf("%s: %w", ping.Info(), err)
+	}
+	if _, ok := response.(*command.Pong); !ok {
+		return fmt.Errorf("%s: expected PONG, got %s", ping.Info(), response.Info())
+	}
+	return nil
+}
+
+func checkAckOrdering(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+
+	// The commands are sent at once, each one depending on the previous ones being processed.
+	name := uniqueName("conformance")
+	target.DeleteAfter(name)
+	commands := []command.Command{
+		command.NewCreateTunnel(name),
+		command.NewListenTunnel(name),
+		command.NewDescribeTunnel(name),
+		command.NewUnlistenTunnel(name),
+		command.NewDeleteTunnel(name),
+	}
+	payload := bytes.Buffer{}
+	for _, cmd := range commands {
+		payload.Write(session.Framing().Marshal(cmd))
+	}
+	err = session.SendRaw(payload.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// The responses must be received in the order of the commands.
+	for _, cmd := range commands {
+		response, err := session.Receive()
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmd.Info(), err)
+		}
+		if response.TransactionID() != cmd.TransactionID() {
+			return fmt.Errorf("%s: expected its response, got %s (transaction %s)", cmd.Info(), response.Info(), response.TransactionID())
+		}
+		switch cmd.(type) {
+		case *command.DescribeTunnel:
+			info, ok := response.(*command.TunnelInfo)
+			if !ok || len(info.Tunnels) != 1 || info.Tunnels[0].Listeners != 1 {
+				return fmt.Errorf("%s: expected TUNNEL_INFO with 1 listener, got %s", cmd.Info(), response.Info())
+			}
+		default:
+			if _, ok := response.(*command.Ack); !ok {
+				return fmt.Errorf("%s: expected ACK, got %s", cmd.Info(), response.Info())
+			}
+		}
+	}
+
+	err = session.ExpectSilence(silence)
+	if err != nil {
+		return fmt.Errorf("each command must be answered once: %w", err)
+	}
+	return nil
+}
+
+func checkUnknownIndicator(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+
+	transactionID := command.NewPing().TransactionID()
+	framing := session.Framing()
+	err = session.SendRaw(rawPayload(framing, unknownIndicator, transactionID, []byte("unknown")))
+	if err != nil {
+		return err
+	}
+	response, err := session.Response(transactionID)
+	if err != nil {
+		return fmt.Errorf("unknown indicator: %w", err)
+	}
+	err = checkNack("unknown indicator", response, command.NackUnsupportedCommand)
+	if err != nil {
+		return err
+	}
+
+	// The connection must keep working.
+	return expectNack(session, command.NewDescribeTunnel(uniqueName("conformance")), command.NackTunnelNotFound)
+}
+
+func checkMalformedCommand(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+
+	// The Tunnel of unknown type must not be created, deleted anyway.
+	unknownType := uniqueName("conformance")
+	target.DeleteAfter(unknownType)
+
+	framing := session.Framing()
+	for _, raw := range []struct {
+		indicator byte
+		data      string
+	}{
+		{indicator: command.CreateTunnelIndicator, data: "\x09" + unknownType},
+		{indicator: command.CreateTunnelIndicator, data: "\x00invalid name!"},
+		{indicator: command.ListenTunnelIndicator, data: ""},
+		{indicator: command.PublishMessageIndicator, data: "no-separator"},
+	} {
+		transactionID := command.NewPing().TransactionID()
+		err = session.SendRaw(rawPayload(framing, raw.indicator, transactionID, []byte(raw.data)))
+		if err != nil {
+			return err
+		}
+		response, err := session.Response(transactionID)
+		if err != nil {
+			return fmt.Errorf("malformed %q: %w", string(raw.indicator)+raw.data, err)
+		}
+		err = checkNack(fmt.Sprintf("malformed %q", string(raw.indicator)+raw.data), response, command.NackMalformedCommand)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkMalformedFrame(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+
+	// The transaction id is invalid, the payload cannot be acknowledged: it's discarded or the connection is closed.
+	err = session.SendRaw(rawPayload(session.Framing(), command.HeartbeatIndicator, "bad tid!", []byte("PING")))
+	if err != nil {
+		return err
+	}
+	describe := command.NewDescribeTunnel(uniqueName("conformance"))
+	err = session.Send(describe)
+	if err != nil {
+		return nil // Closed
+	}
+	response, err := session.Receive()
+	if errors.Is(err, errClosed) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("the connection must keep working or be closed: %w", err)
+	}
+	if response.TransactionID() != describe.TransactionID() {
+		return fmt.Errorf("the malformed payload must not be answered, got %s", response.Info())
+	}
+	err = checkNack(describe.Info(), response, command.NackTunnelNotFound)
+	if err != nil {
+		return err
+	}
+
+	if session.Framing() != pdu.LengthPrefixedFraming {
+		return nil
+	}
+	return checkOversizedLength(target)
+}
+
+func checkOversizedLength(target *Target) error {
+	// The declared length exceeds any max frame size, the connection must be closed without waiting for the data.
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+	payload := append([]byte{command.HeartbeatIndicator}, command.NewPing().TransactionID()...)
+	err = session.SendRaw(binary.AppendUvarint(payload, 1<<50))
+	if err != nil {
+		return nil // Closed while sending
+	}
+	err = session.ExpectClosed()
+	if err != nil {
+		return fmt.Errorf("oversized length prefix: %w", err)
+	}
+	return nil
+}
+
+func checkMaxFrameSize(target *Target) error {
+	session, err := target.Connect()
+	if err != nil {
+		return err
+	}
+	if session.Hello == nil || session.Hello.MaxFrameSize == 0 || session.Hello.MaxFrameSize >= maxFrameSize {
+		return fmt.Errorf("%w: no max frame size lower than %d agreed", ErrSkipped, maxFrameSize)
+	}
+
+	// Publishing to an unknown Tunnel, a server not closing the connection nacks it.
+	publish := command.NewPublishMessage(uniqueName("conformance"), bytes.Repeat([]byte("a"), int(session.Hello.MaxFrameSize)))
+	err = session.Send(publish)
+	if err != nil {
+		return nil // Closed while sending
+	}
+	return session.ExpectClosed()
+}
+
+// rawPayload builds a payload that could be refused by the Framing.
+func rawPayload(framing pdu.Framing, indicator byte, transactionID string, data []byte) []byte {
+	payload := []byte{indicator}
+	payload = append(payload, transactionID...)
+	if framing == pdu.LengthPrefixedFraming {
+		payload = binary.AppendUvarint(payload, uint64(len(data)))
+		return append(payload, data...)
+	}
+	payload = append(payload, data...)
+	return append(payload, pdu.Delimiter)
+}
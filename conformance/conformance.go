@@ -0,0 +1,123 @@
+// Package conformance checks that a Tunnel server follows the protocol described in doc/commands.adoc and
+// doc/payloads.adoc, by running scripted exchanges of raw payloads against it.
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+const defaultTimeout = 2 * time.Second
+
+// ErrSkipped is returned (wrapped) by a Check that cannot run against the server, i.e. missing capability.
+var ErrSkipped = errors.New("skipped")
+
+// Check is a scripted exchange with the server.
+// Run returns an error describing the deviation from the protocol, nil if the server behaved as expected.
+type Check struct {
+	Name string
+	Run  func(target *Target) error
+}
+
+type Option struct {
+	// Timeout is the maximum duration to wait for each expected server command. Default 2 seconds.
+	Timeout time.Duration
+
+	// Capabilities offered during the handshake of every connection. Default all the capabilities of the protocol.
+	Capabilities []command.Capability
+
+	// Checks to run. Default Checks().
+	Checks []Check
+}
+
+func (opts *Option) defaults() {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Capabilities == nil {
+		opts.Capabilities = command.Capabilities()
+	}
+	if opts.Checks == nil {
+		opts.Checks = Checks()
+	}
+}
+
+// Result of a Check.
+type Result struct {
+	Check string
+	// Err is the deviation from the protocol, nil if the Check passed.
+	Err      error
+	Duration time.Duration
+}
+
+// Passed determines if the server behaved as expected.
+func (r Result) Passed() bool { return r.Err == nil }
+
+// Skipped determines if the Check couldn't run against the server.
+func (r Result) Skipped() bool { return errors.Is(r.Err, ErrSkipped) }
+
+func (r Result) String() string {
+	switch {
+	case r.Passed():
+		return fmt.Sprintf("PASS %s (%s)", r.Check, r.Duration.Round(time.Millisecond))
+	case r.Skipped():
+		return fmt.Sprintf("SKIP %s: %s", r.Check, r.Err)
+	default:
+		return fmt.Sprintf("FAIL %s: %s", r.Check, r.Err)
+	}
+}
+
+// Report holds the Result of every Check, in the order they ran.
+type Report struct {
+	Addr    string
+	Results []Result
+}
+
+// Passed determines if no Check failed (skipped ones don't count).
+func (r *Report) Passed() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures returns the Result of the failed Checks.
+func (r *Report) Failures() []Result {
+	var failures []Result
+	for _, result := range r.Results {
+		if !result.Passed() && !result.Skipped() {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+func (r *Report) String() string {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "Conformance of %s: %d checks, %d failed\n", r.Addr, len(r.Results), len(r.Failures()))
+	for _, result := range r.Results {
+		builder.WriteString(result.String())
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+// Run the checks against the Tunnel server listening on addr. opts can be nil.
+// Each Check runs on its own connections, closed once it's done.
+func Run(addr string, opts *Option) *Report {
+	if opts == nil {
+		opts = &Option{}
+	}
+	opts.defaults()
+
+	report := &Report{Addr: addr}
+	for _, check := range opts.Checks {
+		target := &Target{addr: addr, opts: opts}
+		start := time.Now()
+		err := check.Run(target)
+		target.close()
+		report.Results = append(report.Results, Result{Check: check.Name, Err: err, Duration: time.Since(start)})
+	}
+	return report
+}
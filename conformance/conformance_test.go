@@ -0,0 +1,370 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go"
+	"github.com/codingLayce/tunnel.go/id"
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+	"github.com/codingLayce/tunnel.go/tcp"
+)
+
+const referenceMaxFrameSize = 64 * 1024
+
+var knownIndicators = []byte{
+	command.AcknowledgementIndicator, command.CreateTunnelIndicator, command.ListenTunnelIndicator,
+	command.PublishMessageIndicator, command.ReceiveMessageIndicator, command.HelloIndicator,
+	command.UnlistenTunnelIndicator, command.DeleteTunnelIndicator, command.ListTunnelsIndicator,
+	command.DescribeTunnelIndicator, command.TunnelInfoIndicator, command.HeartbeatIndicator,
+}
+
+type referencePeer struct {
+	conn        *tcp.Connection
+	framing     pdu.Framing
+	compression pdu.Compression
+	patterns    map[string]bool
+	// capabilities agreed during the handshake.
+	capabilities []command.Capability
+}
+
+func (peer *referencePeer) has(capability command.Capability) bool {
+	return slices.Contains(peer.capabilities, capability)
+}
+
+type referenceTunnel struct {
+	tunnelType command.TunnelType
+	listeners  map[string]*referencePeer
+	next       int
+}
+
+// referenceServer is a minimal Tunnel server following the protocol, the conformance checks must pass against it.
+type referenceServer struct {
+	*tcp.Server
+
+	// ignoreUnknownIndicators makes the server deviate from the protocol.
+	ignoreUnknownIndicators bool
+
+	peers   map[string]*referencePeer
+	tunnels map[string]*referenceTunnel
+	mtx     sync.Mutex
+}
+
+func startReferenceServer(t *testing.T, configure func(srv *referenceServer)) *referenceServer {
+	srv := &referenceServer{
+		peers:   make(map[string]*referencePeer),
+		tunnels: make(map[string]*referenceTunnel),
+	}
+	if configure != nil {
+		configure(srv)
+	}
+	srv.Server = tcp.NewServer(&tcp.ServerOption{
+		Addr:         "127.0.0.1:0",
+		MaxFrameSize: referenceMaxFrameSize,
+		OnConnectionReceived: func(conn *tcp.Connection) {
+			srv.mtx.Lock()
+			defer srv.mtx.Unlock()
+			srv.peers[conn.ID] = &referencePeer{conn: conn, patterns: make(map[string]bool)}
+		},
+		OnConnectionClosed: func(conn *tcp.Connection, _ bool) {
+			srv.mtx.Lock()
+			defer srv.mtx.Unlock()
+			delete(srv.peers, conn.ID)
+			for _, tunnel := range srv.tunnels {
+				delete(tunnel.listeners, conn.ID)
+			}
+		},
+		OnPayload: srv.onPayload,
+	})
+	require.NoError(t, srv.Start())
+	t.Cleanup(srv.Stop)
+	return srv
+}
+
+func (srv *referenceServer) send(peer *referencePeer, cmd command.Command) {
+	_ = peer.conn.Send(peer.framing.Marshal(cmd))
+}
+
+func (srv *referenceServer) onPayload(conn *tcp.Connection, payload []byte) {
+	srv.mtx.Lock()
+	defer srv.mtx.Unlock()
+	peer := srv.peers[conn.ID]
+
+	cmd, err := peer.framing.UnmarshalCompressed(payload, peer.compression, referenceMaxFrameSize)
+	if err != nil {
+		transactionID := string(payload[1:min(len(payload), 9)])
+		if !id.IsValid(transactionID) {
+			return // Cannot be acknowledged
+		}
+		nack := command.NewNackWithTransactionID(transactionID)
+		nack.Code = command.NackMalformedCommand
+		if !strings.ContainsRune(string(knownIndicators), rune(payload[0])) {
+			if srv.ignoreUnknownIndicators {
+				return
+			}
+			nack.Code = command.NackUnsupportedCommand
+		}
+		srv.send(peer, nack)
+		return
+	}
+
+	switch castedCMD := cmd.(type) {
+	case *command.Hello:
+		supported := command.NewHello()
+		supported.MaxFrameSize = referenceMaxFrameSize
+		supported.Capabilities = command.Capabilities()
+		agreed := supported.Negotiate(castedCMD)
+		srv.send(peer, agreed)
+		peer.capabilities = agreed.Capabilities
+		if agreed.Has(command.LengthPrefixedFramingCapability) {
+			peer.framing = pdu.LengthPrefixedFraming
+			peer.compression = pdu.NegotiatedCompression(agreed.Capabilities)
+			conn.SetFraming(peer.framing)
+		}
+	case *command.Ping:
+		srv.send(peer, command.NewPongWithTransactionID(cmd.TransactionID()))
+	case *command.CreateTunnel:
+		srv.respond(peer, cmd, srv.createTunnel(castedCMD))
+	case *command.ListenTunnel:
+		srv.respond(peer, cmd, srv.listenTunnel(peer, castedCMD.Name))
+	case *command.UnlistenTunnel:
+		srv.respond(peer, cmd, srv.unlistenTunnel(peer, castedCMD.Name))
+	case *command.DeleteTunnel:
+		srv.respond(peer, cmd, srv.deleteTunnel(castedCMD.Name))
+	case *command.PublishMessage:
+		srv.respond(peer, cmd, srv.publish(peer, castedCMD))
+	case *command.DescribeTunnel:
+		tunnel, ok := srv.tunnels[castedCMD.Name]
+		if !ok {
+			srv.respond(peer, cmd, nackCode(command.NackTunnelNotFound))
+			return
+		}
+		srv.send(peer, command.NewTunnelInfoWithTransactionID(cmd.TransactionID(), srv.describe(castedCMD.Name, tunnel)))
+	case *command.ListTunnels:
+		info := command.NewTunnelInfoWithTransactionID(cmd.TransactionID())
+		for name, tunnel := range srv.tunnels {
+			if castedCMD.Match(name) {
+				info.Tunnels = append(info.Tunnels, srv.describe(name, tunnel))
+			}
+		}
+		srv.send(peer, info)
+	case *command.Ack, *command.Nack:
+	default:
+		srv.respond(peer, cmd, nackCode(command.NackUnsupportedCommand))
+	}
+}
+
+// respond acks the command, or nacks it with the code.
+func (srv *referenceServer) respond(peer *referencePeer, cmd command.Command, code *command.NackCode) {
+	if code == nil {
+		srv.send(peer, command.NewAckWithTransactionID(cmd.TransactionID()))
+		return
+	}
+	nack := command.NewNackWithTransactionID(cmd.TransactionID())
+	nack.Code = *code
+	srv.send(peer, nack)
+}
+
+func nackCode(code command.NackCode) *command.NackCode { return &code }
+
+func (srv *referenceServer) describe(name string, tunnel *referenceTunnel) command.TunnelDescription {
+	return command.TunnelDescription{Name: name, Type: tunnel.tunnelType, Listeners: len(tunnel.listeners)}
+}
+
+func (srv *referenceServer) createTunnel(cmd *command.CreateTunnel) *command.NackCode {
+	if _, ok := srv.tunnels[cmd.Name]; ok {
+		return nackCode(command.NackTunnelExists)
+	}
+	srv.tunnels[cmd.Name] = &referenceTunnel{tunnelType: cmd.Type, listeners: make(map[string]*referencePeer)}
+	return nil
+}
+
+func (srv *referenceServer) listenTunnel(peer *referencePeer, name string) *command.NackCode {
+	if command.IsTopicPattern(name) {
+		peer.patterns[name] = true
+		return nil
+	}
+	tunnel, ok := srv.tunnels[name]
+	if !ok {
+		return nackCode(command.NackTunnelNotFound)
+	}
+	tunnel.listeners[peer.conn.ID] = peer
+	return nil
+}
+
+func (srv *referenceServer) unlistenTunnel(peer *referencePeer, name string) *command.NackCode {
+	if peer.patterns[name] {
+		delete(peer.patterns, name)
+		return nil
+	}
+	tunnel, ok := srv.tunnels[name]
+	if !ok || tunnel.listeners[peer.conn.ID] == nil {
+		return nackCode(command.NackUnknown)
+	}
+	delete(tunnel.listeners, peer.conn.ID)
+	return nil
+}
+
+func (srv *referenceServer) deleteTunnel(name string) *command.NackCode {
+	tunnel, ok := srv.tunnels[name]
+	if !ok {
+		return nackCode(command.NackTunnelNotFound)
+	}
+	delete(srv.tunnels, name)
+	for _, listener := range tunnel.listeners {
+		srv.send(listener, command.NewDeleteTunnel(name))
+	}
+	return nil
+}
+
+func (srv *referenceServer) publish(publisher *referencePeer, cmd *command.PublishMessage) *command.NackCode {
+	tunnel, ok := srv.tunnels[cmd.TunnelName]
+	if !ok {
+		return nackCode(command.NackTunnelNotFound)
+	}
+	if tunnel.listeners[publisher.conn.ID] == nil && !publisher.has(command.OpenPublishCapability) {
+		return nackCode(command.NackNotAuthorized)
+	}
+
+	var recipients []*referencePeer
+	for _, peer := range srv.peers {
+		if peer == publisher {
+			continue
+		}
+		listening := tunnel.listeners[peer.conn.ID] != nil
+		for pattern := range peer.patterns {
+			listening = listening || (tunnel.tunnelType == command.TopicTunnel && command.MatchTopic(pattern, cmd.TunnelName))
+		}
+		if listening {
+			recipients = append(recipients, peer)
+		}
+	}
+	if tunnel.tunnelType == command.QueueTunnel && len(recipients) > 0 {
+		tunnel.next++
+		recipients = recipients[tunnel.next%len(recipients) : tunnel.next%len(recipients)+1]
+	}
+
+	messageID := cmd.MessageID
+	if messageID == "" {
+		messageID = xid.New().String()
+	}
+	for _, recipient := range recipients {
+		message := command.NewReceiveMessage(cmd.TunnelName, cmd.Message)
+		if recipient.has(command.MessageIDCapability) {
+			message.MessageID = messageID
+		}
+		if recipient.has(command.HeadersCapability) {
+			message.Headers = cmd.Headers
+		}
+		srv.send(recipient, message)
+	}
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	srv := startReferenceServer(t, nil)
+
+	report := Run(srv.Addr(), nil)
+
+	assert.True(t, report.Passed(), report.String())
+	assert.Len(t, report.Results, len(Checks()))
+	for _, result := range report.Results {
+		assert.False(t, result.Skipped(), result.String())
+	}
+
+	srv.mtx.Lock()
+	defer srv.mtx.Unlock()
+	assert.Empty(t, srv.tunnels, "The checks must delete their Tunnels")
+}
+
+func TestRun_Deviation(t *testing.T) {
+	srv := startReferenceServer(t, func(srv *referenceServer) {
+		srv.ignoreUnknownIndicators = true
+	})
+
+	report := Run(srv.Addr(), &Option{Timeout: 200 * time.Millisecond})
+
+	assert.False(t, report.Passed())
+	failures := report.Failures()
+	require.Len(t, failures, 1, report.String())
+	assert.Equal(t, "unknown_indicator", failures[0].Check)
+	assert.Contains(t, report.String(), "FAIL unknown_indicator: unknown indicator: timeout waiting for response to ")
+}
+
+func TestRun_WithoutCapabilities(t *testing.T) {
+	srv := startReferenceServer(t, nil)
+
+	report := Run(srv.Addr(), &Option{Capabilities: []command.Capability{}})
+
+	assert.True(t, report.Passed(), report.String())
+	var skipped []string
+	for _, result := range report.Results {
+		if result.Skipped() {
+			skipped = append(skipped, result.Check)
+		}
+	}
+	assert.Equal(t, []string{"queue_tunnel", "topic_tunnel", "open_publish", "heartbeat"}, skipped)
+}
+
+func TestRun_CustomChecks(t *testing.T) {
+	srv := startReferenceServer(t, nil)
+
+	report := Run(srv.Addr(), &Option{Checks: []Check{
+		{Name: "legacy_describe", Run: func(target *Target) error {
+			session, err := target.Dial()
+			if err != nil {
+				return err
+			}
+			return expectNack(session, command.NewDescribeTunnel("unknown"), command.NackTunnelNotFound)
+		}},
+	}})
+
+	assert.True(t, report.Passed(), report.String())
+	assert.Equal(t, []string{"legacy_describe"}, []string{report.Results[0].Check})
+}
+
+func TestRun_Unreachable(t *testing.T) {
+	report := Run("127.0.0.1:1", &Option{Checks: Checks()[:1]})
+
+	assert.False(t, report.Passed())
+	assert.ErrorContains(t, report.Failures()[0].Err, "dial: ")
+}
+
+func TestReferenceServer_Request(t *testing.T) {
+	srv := startReferenceServer(t, nil)
+
+	server, err := tunnel.Connect(srv.Addr())
+	require.NoError(t, err)
+	defer server.Stop()
+	requester, err := tunnel.Connect(srv.Addr())
+	require.NoError(t, err)
+	defer requester.Stop()
+
+	require.NoError(t, server.CreateBTunnel("service"))
+	_, err = server.HandleRequests("service", func(request *tunnel.Message) ([]byte, error) {
+		if string(request.Body) == "fail" {
+			return nil, errors.New("boom")
+		}
+		return append([]byte("pong "), request.Body...), nil
+	})
+	require.NoError(t, err)
+
+	// The requester isn't listening to the service, the handler isn't listening to the reply Tunnel.
+	reply, err := requester.Request(context.Background(), "service", []byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, "pong ping", string(reply.Body))
+
+	_, err = requester.Request(context.Background(), "service", []byte("fail"))
+	assert.ErrorIs(t, err, tunnel.ErrRequestFailed)
+	assert.EqualError(t, err, "request failed: boom")
+}
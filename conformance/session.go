@@ -0,0 +1,298 @@
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+var errClosed = errors.New("connection closed")
+
+// maxFrameSize is the maximum payload size (in bytes) offered to the server.
+const maxFrameSize = 4 * 1024 * 1024
+
+// Target is the server a Check runs against.
+type Target struct {
+	addr     string
+	opts     *Option
+	sessions []*Session
+	// tunnels are deleted once the Check is done.
+	tunnels []string
+}
+
+// DeleteAfter registers a Tunnel created by the Check, deleted once the Check is done so the runs leave the server
+// as they found it. The Tunnels already deleted by the Check are ignored.
+func (t *Target) DeleteAfter(tunnelName string) {
+	t.tunnels = append(t.tunnels, tunnelName)
+}
+
+// Dial opens a connection to the server without handshake (legacy protocol).
+// The connection is closed once the Check is done.
+func (t *Target) Dial() (*Session, error) {
+	conn, err := net.DialTimeout("tcp", t.addr, t.opts.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	session := &Session{
+		target:   t,
+		conn:     conn,
+		timeout:  t.opts.Timeout,
+		decoder:  pdu.NewDecoder(conn, &pdu.DecoderOption{MaxFrameSize: maxFrameSize}),
+		commands: make(chan command.Command, 128),
+		closed:   make(chan struct{}),
+	}
+	t.sessions = append(t.sessions, session)
+	go session.readLoop()
+	return session, nil
+}
+
+// Connect opens a connection to the server and does the handshake, offering the capabilities of the Option.
+// A server not supporting the handshake keeps the legacy protocol.
+// The connection is closed once the Check is done.
+func (t *Target) Connect() (*Session, error) {
+	session, err := t.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	offer := command.NewHello()
+	offer.MaxFrameSize = maxFrameSize
+	offer.Capabilities = t.opts.Capabilities
+	response, err := session.Request(offer)
+	if err != nil {
+		return nil, fmt.Errorf("handshake: %w", err)
+	}
+	switch castedCMD := response.(type) {
+	case *command.Hello:
+		session.agreed(castedCMD)
+	case *command.Nack:
+	default:
+		return nil, fmt.Errorf("handshake: unexpected response %s", response.Info())
+	}
+	return session, nil
+}
+
+func (t *Target) close() {
+	for _, session := range t.sessions {
+		session.Close()
+	}
+	t.deleteTunnels()
+}
+
+func (t *Target) deleteTunnels() {
+	// Best effort, on a new connection once the ones of the Check are closed (their listeners aren't notified).
+	if len(t.tunnels) == 0 {
+		return
+	}
+	session, err := t.Connect()
+	if err != nil {
+		return
+	}
+	defer session.Close()
+	for _, name := range t.tunnels {
+		_, _ = session.Request(command.NewDeleteTunnel(name))
+	}
+	t.tunnels = nil
+}
+
+// Session is a connection to the server exchanging raw payloads.
+// The pings of the server are answered automatically.
+type Session struct {
+	target  *Target
+	conn    net.Conn
+	timeout time.Duration
+	decoder *pdu.Decoder
+
+	// Hello holds the values agreed during the handshake, nil for the legacy protocol.
+	Hello *command.Hello
+
+	framing     pdu.Framing
+	compression pdu.Compression
+	mtx         sync.Mutex
+
+	// commands are the ones read from the connection, pending the ones read but not expected yet.
+	commands chan command.Command
+	pending  []command.Command
+	readErr  error
+	closed   chan struct{}
+}
+
+// Has determines if the capability has been agreed during the handshake.
+func (s *Session) Has(capability command.Capability) bool {
+	return s.Hello != nil && s.Hello.Has(capability)
+}
+
+func (s *Session) agreed(hello *command.Hello) {
+	s.Hello = hello
+	if hello.Has(command.LengthPrefixedFramingCapability) {
+		s.mtx.Lock()
+		s.framing = pdu.LengthPrefixedFraming
+		s.mtx.Unlock()
+	}
+}
+
+// Framing returns the framing used by the connection.
+func (s *Session) Framing() pdu.Framing {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.framing
+}
+
+// Send the command following the framing of the connection.
+func (s *Session) Send(cmd command.Command) error {
+	return s.SendRaw(s.Framing().Marshal(cmd))
+}
+
+// SendRaw sends the payload as is.
+func (s *Session) SendRaw(payload []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	err := s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	if err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	_, err = s.conn.Write(payload)
+	if err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	return nil
+}
+
+// Request sends the command and waits for the server command having the same transaction id.
+func (s *Session) Request(cmd command.Command) (command.Command, error) {
+	err := s.Send(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return s.Response(cmd.TransactionID())
+}
+
+// Response waits for the server command having the given transaction id.
+func (s *Session) Response(transactionID string) (command.Command, error) {
+	return s.Expect(fmt.Sprintf("response to %s", transactionID), func(cmd command.Command) bool {
+		return cmd.TransactionID() == transactionID
+	})
+}
+
+// Receive waits for the next server command.
+func (s *Session) Receive() (command.Command, error) {
+	return s.Expect("command", func(_ command.Command) bool { return true })
+}
+
+// Expect waits for the next server command matching, the other ones are kept for the next expectations.
+// what describes the expected command in the returned errors.
+func (s *Session) Expect(what string, match func(cmd command.Command) bool) (command.Command, error) {
+	for i, cmd := range s.pending {
+		if match(cmd) {
+			s.pending = slices.Delete(s.pending, i, i+1)
+			return cmd, nil
+		}
+	}
+
+	timeout := time.After(s.timeout)
+	for {
+		select {
+		case cmd, ok := <-s.commands:
+			if !ok {
+				return nil, fmt.Errorf("waiting for %s: %w: %w", what, errClosed, s.readErr)
+			}
+			if match(cmd) {
+				return cmd, nil
+			}
+			s.pending = append(s.pending, cmd)
+		case <-timeout:
+			return nil, fmt.Errorf("timeout waiting for %s", what)
+		}
+	}
+}
+
+// ExpectSilence checks that the server doesn't send any command during d.
+func (s *Session) ExpectSilence(d time.Duration) error {
+	if len(s.pending) > 0 {
+		return fmt.Errorf("unexpected command %s", s.pending[0].Info())
+	}
+
+	select {
+	case cmd, ok := <-s.commands:
+		if !ok {
+			return fmt.Errorf("%w: %w", errClosed, s.readErr)
+		}
+		return fmt.Errorf("unexpected command %s", cmd.Info())
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// ExpectClosed checks that the server closes the connection, the commands sent before are discarded.
+func (s *Session) ExpectClosed() error {
+	timeout := time.After(s.timeout)
+	for {
+		select {
+		case _, ok := <-s.commands:
+			if !ok {
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("timeout waiting for the connection to be closed")
+		}
+	}
+}
+
+// Close the connection.
+func (s *Session) Close() {
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+		_ = s.conn.Close()
+	}
+}
+
+func (s *Session) readLoop() {
+	defer close(s.commands)
+
+	for {
+		payload, err := s.decoder.ReadPayload()
+		if err != nil {
+			s.readErr = err
+			return
+		}
+
+		s.mtx.Lock()
+		framing, compression := s.decoder.Framing(), s.compression
+		s.mtx.Unlock()
+
+		cmd, err := framing.UnmarshalCompressed(payload, compression, maxFrameSize)
+		if err != nil {
+			s.readErr = fmt.Errorf("invalid payload %q: %w", payload, err)
+			return
+		}
+
+		switch castedCMD := cmd.(type) {
+		case *command.Hello:
+			// The server switches right after its hello, the next payload must be read with the new framing.
+			if castedCMD.Has(command.LengthPrefixedFramingCapability) {
+				s.decoder.SetFraming(pdu.LengthPrefixedFraming)
+				s.mtx.Lock()
+				s.compression = pdu.NegotiatedCompression(castedCMD.Capabilities)
+				s.mtx.Unlock()
+			}
+		case *command.Ping:
+			_ = s.Send(command.NewPongWithTransactionID(cmd.TransactionID()))
+			continue
+		}
+
+		select {
+		case s.commands <- cmd:
+		case <-s.closed:
+			return
+		}
+	}
+}
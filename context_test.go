@@ -0,0 +1,145 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestClient_PublishMessageContext_Canceled(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-tcpClient.commandsChan(): // Not responding
+			cancel()
+		case <-time.After(100 * time.Millisecond):
+			assert.Fail(t, "Server should have received a PublishMessage command")
+		}
+	}()
+
+	start := time.Now()
+	err = cl.PublishMessageContext(ctx, "Bidule", "message")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Zero(t, cl.waiters.Len(), "The waiter should have been deleted")
+}
+
+func TestClient_CreateBTunnelContext_DeadlineExceeded(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	go func() {
+		<-tcpClient.commandsChan() // Not responding
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = cl.CreateBTunnelContext(ctx, "MyTunnel")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_DeleteTunnelContext_AlreadyCanceled(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	tcpClient.send = func(_ []byte) error {
+		assert.Fail(t, "Nothing should have been sent")
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = cl.DeleteTunnelContext(ctx, "MyTunnel")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClient_ListTunnelsContext_CanceledWhileSending(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	release := make(chan struct{})
+	tcpClient.send = func(_ []byte) error {
+		<-release // Write blocked (i.e. full TCP buffer)
+		return nil
+	}
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = cl.ListTunnelsContext(ctx, "")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_ListenTunnelContext_Canceled(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	go func() {
+		<-tcpClient.commandsChan() // Not responding
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = cl.ListenTunnelContext(ctx, "Bidule", func(_ string) {})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The Subscription has been discarded, listening again is allowed
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnelContext(context.Background(), "Bidule", func(_ string) {})
+	require.NoError(t, err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = sub.UnsubscribeContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, sub.Err(), ErrUnsubscribed, "The Subscription should have ended anyway")
+}
+
+func TestClient_SendCommandContext(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	go func() {
+		cmd := <-tcpClient.commandsChan()
+		tcpClient.callOnPayload(pdu.Marshal(command.NewTunnelInfoWithTransactionID(cmd.TransactionID())))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	response, err := cl.SendCommandContext(ctx, command.NewListTunnels(""))
+	require.NoError(t, err)
+	assert.IsType(t, &command.TunnelInfo{}, response)
+}
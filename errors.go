@@ -0,0 +1,85 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+var (
+	// ErrNotConnected is returned when the Client has no connection with the Tunnel server.
+	ErrNotConnected = errors.New("not connected to Tunnel server")
+	// ErrConnectionLost is returned when the connection with the Tunnel server is lost while waiting for its response.
+	// The server may have processed the command anyway.
+	ErrConnectionLost = errors.New("connection lost with Tunnel server")
+	// ErrTimeout is returned when the Tunnel server doesn't respond in time.
+	ErrTimeout = errors.New("timeout waiting for server acknowledgement")
+	// ErrClientStopped is returned when the Client is stopped while waiting for the Tunnel server.
+	ErrClientStopped = errors.New("client stopped")
+	// ErrReconnectGaveUp is the reason of a Client stopped because its RetryPolicy gave up reconnecting.
+	ErrReconnectGaveUp = errors.New("gave up reconnecting to Tunnel server")
+	// ErrAlreadyListening is returned when listening a Tunnel already listened by the Client.
+	ErrAlreadyListening = errors.New("already listening")
+	// ErrUnsubscribed is the reason of a Subscription ended by Unsubscribe.
+	ErrUnsubscribed = errors.New("unsubscribed")
+	// ErrTunnelDeleted is the reason of a Subscription ended by the deletion of its Tunnel.
+	ErrTunnelDeleted = errors.New("tunnel deleted")
+	// ErrUnsupportedFeature is returned when the feature hasn't been agreed with the Tunnel server during the handshake.
+	ErrUnsupportedFeature = errors.New("feature not supported by the server")
+	// ErrRequestFailed is returned when the handler of a request returns an error.
+	ErrRequestFailed = errors.New("request failed")
+	// ErrRequeue is returned (or wrapped) by a MessageHandler to ask the server to deliver the message again.
+	ErrRequeue = errors.New("requeue")
+	// ErrQueueFull is the reason of a message requeued because its Subscription's queue is full (see MaxQueuedMessages).
+	// It matches ErrRequeue.
+	ErrQueueFull = fmt.Errorf("%w: subscription queue full", ErrRequeue)
+	// ErrInvalidOption is returned by Connect when an Option has an invalid value.
+	ErrInvalidOption = errors.New("invalid option")
+	// ErrDuplicateTransactionID is returned when sending a command whose transaction id is still waiting for a response.
+	ErrDuplicateTransactionID = errors.New("transaction id already pending")
+
+	// ErrNack is matched by every NackError.
+	ErrNack = errors.New("server nack")
+	// ErrTunnelNotFound is matched by a NackError when the Tunnel doesn't exist.
+	ErrTunnelNotFound = errors.New("tunnel not found")
+	// ErrTunnelExists is matched by a NackError when the Tunnel already exists.
+	ErrTunnelExists = errors.New("tunnel already exists")
+	// ErrNotAuthorized is matched by a NackError when the Client is not allowed to perform the command.
+	ErrNotAuthorized = errors.New("not authorized")
+	// ErrUnsupportedCommand is matched by a NackError when the Tunnel server doesn't support the command.
+	ErrUnsupportedCommand = errors.New("unsupported command")
+)
+
+var nackErrors = map[command.NackCode]error{
+	command.NackTunnelNotFound:     ErrTunnelNotFound,
+	command.NackTunnelExists:       ErrTunnelExists,
+	command.NackNotAuthorized:      ErrNotAuthorized,
+	command.NackUnsupportedCommand: ErrUnsupportedCommand,
+}
+
+// NackError is returned when the Tunnel server doesn't acknowledge a command.
+// It matches ErrNack and the error corresponding to its Code (see errors.Is).
+type NackError struct {
+	Code   command.NackCode
+	Reason string
+}
+
+func newNackError(nack *command.Nack) *NackError {
+	return &NackError{Code: nack.Code, Reason: nack.Reason}
+}
+
+func (e *NackError) Error() string {
+	msg := "server nack"
+	if e.Code != command.NackUnknown {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Code)
+	}
+	if e.Reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Reason)
+	}
+	return msg
+}
+
+func (e *NackError) Is(target error) bool {
+	return target == ErrNack || target == nackErrors[e.Code]
+}
@@ -0,0 +1,30 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestNackError_Error(t *testing.T) {
+	assert.EqualError(t, &NackError{}, "server nack")
+	assert.EqualError(t, &NackError{Code: command.NackTunnelNotFound}, "server nack (tunnel_not_found)")
+	assert.EqualError(t, &NackError{Reason: "Bidule"}, "server nack: Bidule")
+	assert.EqualError(t, &NackError{Code: command.NackTunnelExists, Reason: "Bidule"}, "server nack (tunnel_exists): Bidule")
+}
+
+func TestNackError_Is(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &NackError{Code: command.NackTunnelNotFound})
+	assert.ErrorIs(t, err, ErrNack)
+	assert.ErrorIs(t, err, ErrTunnelNotFound)
+	assert.NotErrorIs(t, err, ErrTunnelExists)
+	assert.NotErrorIs(t, &NackError{}, ErrTunnelNotFound)
+
+	var nackErr *NackError
+	assert.True(t, errors.As(err, &nackErr))
+	assert.Equal(t, command.NackTunnelNotFound, nackErr.Code)
+}
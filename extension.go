@@ -0,0 +1,96 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// CommandHandler handles a command initiated by the server.
+// The command is acknowledged when nil is returned, otherwise it's nacked with the error as reason.
+type CommandHandler func(cmd command.Command) error
+
+// HandleCommand registers the handler of the server-initiated commands with the given indicator.
+// It allows handling commands unknown to the SDK (their parser must be registered with command.Register).
+// The handler is invoked in its own goroutine, so it can send commands.
+func (c *Client) HandleCommand(indicator byte, handler CommandHandler) {
+	c.commandHandlers.Put(indicator, handler)
+}
+
+// SendCommand sends the given command and waits for the server's response.
+// The response is either an Ack or any command replying with the same transaction id.
+// A Nack is returned as a NackError.
+func (c *Client) SendCommand(cmd command.Command) (command.Command, error) {
+	return c.SendCommandContext(context.Background(), cmd)
+}
+
+// SendCommandContext is SendCommand, returning ctx.Err() if the context is done before the server's response.
+func (c *Client) SendCommandContext(ctx context.Context, cmd command.Command) (command.Command, error) {
+	response, err := c.sendCommandAndWaitResponse(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if nack, ok := response.(*command.Nack); ok {
+		return nil, newNackError(nack)
+	}
+	return response, nil
+}
+
+func (c *Client) commandReceived(cmd command.Command) {
+	if c.waiters.Has(cmd.TransactionID()) {
+		c.responseReceived(cmd)
+		return
+	}
+
+	handler, ok := c.commandHandlers.Get(cmd.Indicator())
+	if !ok {
+		c.Logger.Warn("Received unsupported command", "command", cmd.Info())
+		c.rejectCommand(cmd.TransactionID(), command.NackUnsupportedCommand, "command not supported by the client")
+		return
+	}
+
+	started := c.spawn(func() {
+		var response command.Command = command.NewAckWithTransactionID(cmd.TransactionID())
+		err := handler(cmd)
+		if err != nil {
+			nack := command.NewNackWithTransactionID(cmd.TransactionID())
+			nack.Reason = strings.ReplaceAll(err.Error(), "\n", " ")
+			response = nack
+		}
+
+		_, err = c.transmit(context.Background(), response)
+		if err != nil {
+			c.Logger.Warn("Cannot acknowledge the command", "error", err, "transaction_id", cmd.TransactionID())
+		}
+	})
+	if !started {
+		c.Logger.Debug("Client stopped. Command not handled", "command", cmd.Info())
+	}
+}
+
+func (c *Client) rejectPayload(payload []byte, err error) {
+	// Nacks the payload which cannot be parsed. It cannot be nacked if its transaction id is invalid,
+	// and an acknowledgement is never acknowledged.
+	indicator, transactionID, ok := pdu.Header(payload)
+	if !ok || indicator == command.AcknowledgementIndicator {
+		return
+	}
+	code := command.NackMalformedCommand
+	if errors.Is(err, command.ErrUnknownIndicator) {
+		code = command.NackUnsupportedCommand
+	}
+	c.rejectCommand(transactionID, code, err.Error())
+}
+
+func (c *Client) rejectCommand(transactionID string, code command.NackCode, reason string) {
+	nack := command.NewNackWithTransactionID(transactionID)
+	nack.Code = code
+	nack.Reason = strings.ReplaceAll(reason, "\n", " ")
+	_, err := c.transmit(context.Background(), nack)
+	if err != nil {
+		c.Logger.Warn("Cannot nack the command", "error", err, "transaction_id", transactionID)
+	}
+}
@@ -0,0 +1,177 @@
+package tunnel
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+const customIndicator byte = 'c'
+
+type customCommand struct {
+	transactionID string
+	data          []byte
+}
+
+func parseCustomCommand(transactionID string, data []byte) (command.Command, error) {
+	return &customCommand{transactionID: transactionID, data: data}, nil
+}
+
+func (cmd *customCommand) Validate() error       { return nil }
+func (cmd *customCommand) Info() string          { return "CUSTOM" }
+func (cmd *customCommand) TransactionID() string { return cmd.transactionID }
+func (cmd *customCommand) Indicator() byte       { return customIndicator }
+func (cmd *customCommand) Data() []byte          { return cmd.data }
+
+func TestClient_HandleCommand(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	received := make(chan command.Command, 1)
+	cl.HandleCommand(customIndicator, func(cmd command.Command) error {
+		received <- cmd
+		if string(cmd.Data()) == "fail" {
+			return errors.New("cannot\nhandle") // Sent on a single line
+		}
+		return nil
+	})
+
+	go tcpClient.callOnPayload(pdu.Marshal(&customCommand{transactionID: "abcd1234", data: []byte("data")}))
+
+	select {
+	case cmd := <-received:
+		assert.Equal(t, &customCommand{transactionID: "abcd1234", data: []byte("data")}, cmd)
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "The handler should have been invoked")
+	}
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		assert.Equal(t, command.NewAckWithTransactionID("abcd1234"), cmd)
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "A ack should have been received server side")
+	}
+
+	go tcpClient.callOnPayload(pdu.Marshal(&customCommand{transactionID: "efgh5678", data: []byte("fail")}))
+
+	<-received
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		nack, ok := cmd.(*command.Nack)
+		require.True(t, ok)
+		assert.Equal(t, "efgh5678", nack.TransactionID())
+		assert.Equal(t, "cannot handle", nack.Reason)
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "A nack should have been received server side")
+	}
+}
+
+func TestClient_HandleCommand_Stopped(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	cl.HandleCommand(customIndicator, func(_ command.Command) error {
+		assert.Fail(t, "The handler shouldn't have been invoked")
+		return nil
+	})
+	cl.Stop()
+
+	// Received while stopping, not handled once Stop has waited for the routines.
+	tcpClient.callOnPayload(pdu.Marshal(&customCommand{transactionID: "abcd1234", data: []byte("data")}))
+}
+
+func TestClient_UnsupportedCommand(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	for _, tc := range []struct {
+		payload      []byte
+		expectedCode command.NackCode
+	}{
+		{payload: []byte("$abcd1234data\n"), expectedCode: command.NackUnsupportedCommand},
+		{payload: pdu.Marshal(&customCommand{transactionID: "abcd1234", data: []byte("data")}), expectedCode: command.NackUnsupportedCommand},
+		{payload: pdu.Marshal(command.NewCreateTunnelWithTransactionID("abcd1234", "Bidule")), expectedCode: command.NackUnsupportedCommand},
+		{payload: []byte{command.HeartbeatIndicator, 'a', 'b', 'c', 'd', '1', '2', '3', '4', 'X', '\n'}, expectedCode: command.NackMalformedCommand},
+	} {
+		go tcpClient.callOnPayload(tc.payload)
+
+		cmd := receiveCommand(t, tcpClient)
+		nack, ok := cmd.(*command.Nack)
+		require.True(t, ok, "expected a nack for %q, got %s", tc.payload, cmd.Info())
+		assert.Equal(t, "abcd1234", nack.TransactionID())
+		assert.Equal(t, tc.expectedCode, nack.Code)
+	}
+
+	// An invalid acknowledgement and a payload with an invalid transaction id cannot be nacked.
+	tcpClient.callOnPayload([]byte("@abcd1234invalid\n"))
+	tcpClient.callOnPayload([]byte("$ab$%1234data\n"))
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		assert.Fail(t, "Nothing should have been sent", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_SendCommand(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			slog.Debug("[SERVER] Received command", "command", cmd.Info())
+			assert.Equal(t, []byte("request"), cmd.Data())
+			// Replying with a custom command
+			tcpClient.callOnPayload(pdu.Marshal(&customCommand{transactionID: cmd.TransactionID(), data: []byte("response")}))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received the custom command")
+		}
+	}()
+
+	response, err := cl.SendCommand(&customCommand{transactionID: "abcd1234", data: []byte("request")})
+	require.NoError(t, err)
+	assert.Equal(t, &customCommand{transactionID: "abcd1234", data: []byte("response")}, response)
+}
+
+func TestClient_SendCommand_Nack(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			nack := command.NewNackWithTransactionID(cmd.TransactionID())
+			nack.Code = command.NackUnsupportedCommand
+			tcpClient.callOnPayload(pdu.Marshal(nack))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received the custom command")
+		}
+	}()
+
+	_, err = cl.SendCommand(&customCommand{transactionID: "abcd1234", data: []byte("request")})
+	assert.ErrorIs(t, err, ErrUnsupportedCommand)
+}
@@ -3,12 +3,12 @@ module github.com/codingLayce/tunnel.go
 go 1.23.1
 
 require (
+	github.com/rs/xid v1.6.0
 	github.com/stretchr/testify v1.10.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/rs/xid v1.6.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,92 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// MessageHandler processes a message received from a listened Tunnel (see ListenTunnelHandler).
+// The server is acknowledged once the handler returns, so a message whose processing is interrupted
+// (i.e. the process crashes) is not acknowledged:
+//   - nil acks the message
+//   - an error matching ErrRequeue asks the server to deliver the message again
+//   - any other error nacks the message, the server drops it
+//
+// The messages of a Subscription are handled one at a time, in the order they are received.
+// They are queued while the handler is running, up to MaxQueuedMessages: the next ones are requeued with ErrQueueFull,
+// so the server delivers them again later.
+type MessageHandler func(msg *Message) error
+
+// delivery is a received message handed to a Subscription, settled with the result of its handler.
+type delivery struct {
+	message    *Message
+	settlement *settlement
+}
+
+func (d *delivery) settle(err error) {
+	d.settlement.settle(true, err)
+}
+
+func (d *delivery) drop() {
+	// The Subscription has ended before handling the message.
+	d.settlement.settle(false, nil)
+}
+
+// settlement acknowledges a received message once all the Subscriptions it has been delivered to have settled it.
+type settlement struct {
+	client        *Client
+	transactionID string
+	// lost is closed when the connection the message has been received from is lost, acknowledging it is useless.
+	lost <-chan struct{}
+
+	mtx       sync.Mutex
+	remaining int
+	delivered bool
+	err       error
+}
+
+func (s *settlement) settle(delivered bool, err error) {
+	s.mtx.Lock()
+	s.remaining--
+	s.delivered = s.delivered || delivered
+	// A requeue wins over a failure, a failure over a success.
+	if err != nil && (s.err == nil || errors.Is(err, ErrRequeue)) {
+		s.err = err
+	}
+	done := s.remaining == 0 && s.delivered
+	s.mtx.Unlock()
+
+	if done {
+		s.client.acknowledge(s.transactionID, s.lost, s.err)
+	}
+}
+
+func (c *Client) acknowledge(transactionID string, lost <-chan struct{}, err error) {
+	// Acks the received message, or nacks it with the handler's error.
+	select {
+	case <-lost:
+		c.Logger.Debug("Connection lost, cannot acknowledge the message", "transaction_id", transactionID)
+		return
+	default:
+	}
+
+	var cmd command.Command = command.NewAckWithTransactionID(transactionID)
+	if err != nil {
+		nack := command.NewNackWithTransactionID(transactionID)
+		nack.Code = command.NackInternalError
+		if errors.Is(err, ErrRequeue) {
+			nack.Code = command.NackRequeue
+		}
+		nack.Reason = strings.ReplaceAll(err.Error(), "\n", " ")
+		cmd = nack
+	}
+
+	_, err = c.transmit(context.Background(), cmd)
+	if err != nil {
+		c.Logger.Warn("Cannot acknowledge the message", "error", err, "transaction_id", transactionID)
+	}
+}
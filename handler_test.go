@@ -0,0 +1,205 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestClient_ListenTunnelHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected command.Command
+	}{
+		{
+			name:     "ack",
+			expected: &command.Ack{},
+		},
+		{
+			name:     "nack",
+			err:      errors.New("invalid order\nmissing id"),
+			expected: &command.Nack{Code: command.NackInternalError, Reason: "invalid order missing id"},
+		},
+		{
+			name:     "requeue",
+			err:      fmt.Errorf("%w: database unavailable", ErrRequeue),
+			expected: &command.Nack{Code: command.NackRequeue, Reason: "requeue: database unavailable"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tcpClient := newTestTCPClient()
+			mockNewTCPClient(t, tcpClient)
+
+			cl, err := Connect("")
+			require.NoError(t, err)
+			defer cl.Stop()
+
+			release := make(chan struct{})
+			replyNext(t, tcpClient, ack)
+			_, err = cl.ListenTunnelHandler("Orders", func(msg *Message) error {
+				assert.Equal(t, "Mon super message !", string(msg.Body))
+				<-release
+				return tc.err
+			})
+			require.NoError(t, err)
+
+			message := command.NewReceiveMessage("Orders", []byte("Mon super message !"))
+			go tcpClient.callOnPayload(pdu.Marshal(message))
+
+			// Not acknowledged while the handler is running
+			select {
+			case cmd := <-tcpClient.commandsChan():
+				assert.FailNow(t, "Message should not be acknowledged yet", cmd.Info())
+			case <-time.After(50 * time.Millisecond):
+			}
+			close(release)
+
+			cmd := receiveCommand(t, tcpClient)
+			assert.Equal(t, message.TransactionID(), cmd.TransactionID())
+			switch expected := tc.expected.(type) {
+			case *command.Ack:
+				assert.IsType(t, expected, cmd)
+			case *command.Nack:
+				nack, ok := cmd.(*command.Nack)
+				require.True(t, ok, cmd.Info())
+				assert.Equal(t, expected.Code, nack.Code)
+				assert.Equal(t, expected.Reason, nack.Reason)
+			}
+		})
+	}
+}
+
+func TestClient_ListenTunnelHandler_SeveralSubscriptions(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.TopicTunnelCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	_, err = cl.ListenTunnelHandler("orders.eu.created", func(_ *Message) error { return nil })
+	require.NoError(t, err)
+	replyNext(t, tcpClient, ack)
+	_, err = cl.ListenTunnelHandler("orders.*.created", func(_ *Message) error { return errors.New("boom") })
+	require.NoError(t, err)
+
+	message := command.NewReceiveMessage("orders.eu.created", []byte("Mon super message !"))
+	go tcpClient.callOnPayload(pdu.Marshal(message))
+
+	// A single acknowledgement, the failure wins
+	cmd := receiveCommand(t, tcpClient)
+	nack, ok := cmd.(*command.Nack)
+	require.True(t, ok, cmd.Info())
+	assert.Equal(t, message.TransactionID(), nack.TransactionID())
+	assert.Equal(t, command.NackInternalError, nack.Code)
+	select {
+	case cmd = <-tcpClient.commandsChan():
+		assert.Fail(t, "Message should be acknowledged once", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_ListenTunnelHandler_SlowHandler(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	release := make(chan struct{})
+	handled := make(chan string, 3)
+	replyNext(t, tcpClient, ack)
+	_, err = cl.ListenTunnelHandler("Orders", func(msg *Message) error {
+		<-release
+		handled <- string(msg.Body)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The reading of the connection goes on while the handler is running (i.e. the heartbeat pongs are read).
+	messages := []*command.ReceiveMessage{
+		command.NewReceiveMessage("Orders", []byte("first")),
+		command.NewReceiveMessage("Orders", []byte("second")),
+		command.NewReceiveMessage("Orders", []byte("third")),
+	}
+	read := make(chan struct{})
+	go func() {
+		for _, message := range messages {
+			tcpClient.callOnPayload(pdu.Marshal(message))
+		}
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "Receiving messages should not block while the handler is running")
+	}
+
+	// Handled in order
+	close(release)
+	for _, message := range messages {
+		assert.Equal(t, string(message.Message), <-handled)
+		assert.Equal(t, message.TransactionID(), receiveCommand(t, tcpClient).TransactionID())
+	}
+}
+
+func TestClient_ListenTunnelHandler_QueueFull(t *testing.T) {
+	nacks := make(chan *command.Nack, 1)
+	tcpClient := newTestTCPClient()
+	tcpClient.send = func(payload []byte) error {
+		cmd, err := pdu.Unmarshal(payload)
+		require.NoError(t, err)
+		switch castedCmd := cmd.(type) {
+		case *command.ListenTunnel:
+			go tcpClient.callOnPayload(pdu.Marshal(ack(castedCmd)))
+		case *command.Nack:
+			nacks <- castedCmd
+		}
+		return nil
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	handling := make(chan struct{}, 1)
+	_, err = cl.ListenTunnelHandler("Orders", func(_ *Message) error {
+		select {
+		case handling <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The first message is being handled, the next ones fill the queue.
+	tcpClient.callOnPayload(pdu.Marshal(command.NewReceiveMessage("Orders", []byte("handled"))))
+	<-handling
+	for range MaxQueuedMessages {
+		tcpClient.callOnPayload(pdu.Marshal(command.NewReceiveMessage("Orders", []byte("queued"))))
+	}
+
+	overflow := command.NewReceiveMessage("Orders", []byte("overflow"))
+	tcpClient.callOnPayload(pdu.Marshal(overflow))
+	select {
+	case nack := <-nacks:
+		assert.Equal(t, overflow.TransactionID(), nack.TransactionID())
+		assert.Equal(t, command.NackRequeue, nack.Code)
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "The message should have been requeued")
+	}
+}
@@ -0,0 +1,87 @@
+package tunnel
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// maxFrameSize is the maximum payload size (in bytes) the Client offers to receive.
+const maxFrameSize = 4 * 1024 * 1024
+
+// Capabilities are the protocol features agreed with the Tunnel server during the handshake.
+type Capabilities struct {
+	// Version of the protocol. 0 means the server doesn't support the handshake (legacy protocol).
+	Version byte
+	// MaxFrameSize is the maximum payload size in bytes. 0 means unlimited.
+	MaxFrameSize uint32
+	Features     []command.Capability
+}
+
+// Has determines if the given feature has been agreed with the server.
+func (c Capabilities) Has(feature command.Capability) bool {
+	return slices.Contains(c.Features, feature)
+}
+
+// Capabilities returns the capabilities agreed with the Tunnel server during the last handshake.
+func (c *Client) Capabilities() Capabilities {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.capabilities
+}
+
+func (c *Client) handshake() error {
+	// Sends the Client's offer and applies the capabilities agreed by the server.
+	// Servers not supporting the handshake nack it, in that case the legacy protocol is kept.
+	// A server not responding fails the handshake (it may already use the length-prefixed framing), so the
+	// connection is closed.
+	offer := command.NewHelloWithTransactionID(c.newTransactionID())
+	offer.MaxFrameSize = maxFrameSize
+	offer.Capabilities = command.Capabilities() // The Client supports the whole protocol
+	offer.Name = c.name
+
+	response, err := c.exchange(c.ctx, offer, c.ackTimeout, c.transmit)
+	if err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+
+	switch castedResponse := response.(type) {
+	case *command.Hello:
+		agreed := castedResponse.Negotiate(offer)
+		c.mtx.Lock()
+		c.capabilities = Capabilities{
+			Version:      agreed.Version,
+			MaxFrameSize: agreed.MaxFrameSize,
+			Features:     agreed.Capabilities,
+		}
+		c.mtx.Unlock()
+	case *command.Nack:
+		c.Logger.Warn("Tunnel server doesn't support the handshake. Using legacy protocol.")
+	default:
+		return fmt.Errorf("handshake: unexpected response %s", response.Info())
+	}
+
+	c.Logger.Debug("Handshake done", "capabilities", c.Capabilities())
+	return nil
+}
+
+func (c *Client) helloReceived(cmd *command.Hello) {
+	// Invoked by onPayload, so the framing is switched before the next payload is read.
+	if !c.waiters.Has(cmd.TransactionID()) {
+		c.Logger.Warn("Received unexpected hello. Discarding it.")
+		return
+	}
+
+	if cmd.Has(command.LengthPrefixedFramingCapability) {
+		c.mtx.Lock()
+		c.framing = pdu.LengthPrefixedFraming
+		c.internal.SetFraming(c.framing)
+		// Compressed data can only be carried by the length-prefixed framing.
+		c.compression = pdu.NegotiatedCompression(cmd.Capabilities)
+		c.mtx.Unlock()
+	}
+
+	c.responseReceived(cmd)
+}
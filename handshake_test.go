@@ -0,0 +1,118 @@
+package tunnel
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestClient_Handshake(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	tcpClient.hello = func(offer *command.Hello) command.Command {
+		assert.Equal(t, command.ProtocolVersion, offer.Version)
+		assert.Equal(t, uint32(maxFrameSize), offer.MaxFrameSize)
+		assert.Equal(t, command.Capabilities(), offer.Capabilities)
+
+		response := command.NewHelloWithTransactionID(offer.TransactionID())
+		response.MaxFrameSize = 1024
+		response.Capabilities = []command.Capability{command.LengthPrefixedFramingCapability, "unknown"}
+		return response
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	capabilities := cl.Capabilities()
+	assert.Equal(t, command.ProtocolVersion, capabilities.Version)
+	assert.Equal(t, uint32(1024), capabilities.MaxFrameSize)
+	assert.True(t, capabilities.Has(command.LengthPrefixedFramingCapability))
+	assert.False(t, capabilities.Has("unknown"))
+	assert.Equal(t, pdu.LengthPrefixedFraming, tcpClient.framing)
+
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			slog.Debug("[SERVER] Received command", "command", cmd.Info())
+			publishMessage, ok := cmd.(*command.PublishMessage)
+			require.True(t, ok)
+			assert.Equal(t, []byte("Multi\nline"), publishMessage.Message)
+			tcpClient.callOnPayload(tcpClient.framing.Marshal(command.NewAckWithTransactionID(cmd.TransactionID())))
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "Server should have received a PublishMessage command")
+		}
+	}()
+
+	err = cl.PublishMessage("Bidule", "Multi\nline")
+	require.NoError(t, err)
+
+	err = cl.PublishBytes("Bidule", make([]byte, 1024))
+	assert.EqualError(t, err, "frame command: payload size 1042 exceeds the max frame size 1024")
+}
+
+func TestClient_Handshake_LegacyServer(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	tcpClient.hello = func(offer *command.Hello) command.Command {
+		return command.NewNackWithTransactionID(offer.TransactionID())
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	assert.Equal(t, Capabilities{}, cl.Capabilities())
+	assert.Equal(t, pdu.DelimitedFraming, tcpClient.framing)
+}
+
+func TestClient_Handshake_NoResponse(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	tcpClient.hello = func(_ *command.Hello) command.Command {
+		return nil
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	_, err := Connect("", WithAckTimeout(100*time.Millisecond)) // Not too long for tests execution
+	assert.EqualError(t, err, "connect to Tunnel server: handshake: timeout waiting for server acknowledgement")
+}
+
+func TestClient_Handshake_UnexpectedResponse(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	tcpClient.hello = func(offer *command.Hello) command.Command {
+		return command.NewAckWithTransactionID(offer.TransactionID())
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	_, err := Connect("")
+	assert.EqualError(t, err, "connect to Tunnel server: handshake: unexpected response ACK")
+}
+
+func TestClient_Handshake_OnReconnect(t *testing.T) {
+	handshakes := atomic.Int32{}
+	tcpClient := newTestTCPClient()
+	tcpClient.hello = func(offer *command.Hello) command.Command {
+		handshakes.Add(1)
+		return command.NewHelloWithTransactionID(offer.TransactionID())
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+	assert.Equal(t, int32(1), handshakes.Load())
+
+	// Lose the connection, the client reconnects right away
+	tcpClient.disconnect()
+
+	assert.Eventually(t, func() bool {
+		return handshakes.Load() == 2
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
@@ -0,0 +1,93 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// defaultHeartbeatInterval is the delay between two heartbeats, lower than the server read timeout (1 minute)
+// so an idle connection isn't closed.
+const defaultHeartbeatInterval = 20 * time.Second
+
+// SetHeartbeatInterval changes the delay between two heartbeats sent to the Tunnel server.
+// A Pong must be received before the next heartbeat, otherwise the connection is considered dead and is re-established.
+// 0 disables the heartbeats.
+// Heartbeats are only sent when the server supports them (see Capabilities).
+func (c *Client) SetHeartbeatInterval(interval time.Duration) {
+	c.mtx.Lock()
+	c.heartbeatInterval = interval
+	c.mtx.Unlock()
+
+	select { // Wakes up the heartbeat routine, a pending wake up is enough.
+	case c.heartbeatReset <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Client) startHeartbeat() {
+	// Invoked after each successful handshake. The routine ends with the connection.
+	if !c.Capabilities().Has(command.HeartbeatCapability) {
+		c.Logger.Debug("Tunnel server doesn't support heartbeats")
+		return
+	}
+
+	c.mtx.Lock()
+	internal := c.internal
+	c.mtx.Unlock()
+
+	c.wg.Add(1)
+	go c.heartbeatLoop(internal)
+}
+
+func (c *Client) heartbeatLoop(internal TCPClient) {
+	defer c.wg.Done()
+
+	for {
+		c.mtx.Lock()
+		interval := c.heartbeatInterval
+		c.mtx.Unlock()
+
+		var tick <-chan time.Time // nil when disabled: only waits for a new interval
+		if interval > 0 {
+			tick = time.After(interval)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-internal.Done():
+			return
+		case <-c.heartbeatReset:
+			continue
+		case <-tick:
+		}
+
+		err := c.ping(interval)
+		if err != nil {
+			c.Logger.Warn("Missed heartbeat. Closing the connection.", "error", err)
+			internal.Stop()
+			return
+		}
+	}
+}
+
+func (c *Client) ping(timeout time.Duration) error {
+	response, err := c.exchange(context.Background(), command.NewPingWithTransactionID(c.newTransactionID()), timeout, c.transmit)
+	if err != nil {
+		return err
+	}
+	if _, ok := response.(*command.Pong); !ok {
+		return fmt.Errorf("unexpected response %s", response.Info())
+	}
+	return nil
+}
+
+func (c *Client) pingReceived(cmd *command.Ping) {
+	_, err := c.transmit(context.Background(), command.NewPongWithTransactionID(cmd.TransactionID()))
+	if err != nil {
+		c.Logger.Warn("Cannot answer the heartbeat", "error", err, "transaction_id", cmd.TransactionID())
+	}
+}
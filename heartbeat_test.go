@@ -0,0 +1,122 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestClient_Heartbeat(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeartbeatCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	cl.SetHeartbeatInterval(20 * time.Millisecond)
+
+	for range 3 {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			_, isPing := cmd.(*command.Ping)
+			require.True(t, isPing, "Server should have received a Ping command")
+			tcpClient.callOnPayload(pdu.Marshal(command.NewPongWithTransactionID(cmd.TransactionID())))
+		case <-time.After(100 * time.Millisecond):
+			assert.FailNow(t, "Server should have received a Ping command")
+		}
+	}
+}
+
+func TestClient_Heartbeat_MissedPong(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeartbeatCapability)
+	stopped := make(chan struct{}, 1)
+	tcpClient.stop = func() {
+		select {
+		case stopped <- struct{}{}:
+		default:
+		}
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	cl.SetHeartbeatInterval(20 * time.Millisecond)
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		_, isPing := cmd.(*command.Ping)
+		require.True(t, isPing, "Server should have received a Ping command")
+		// No pong
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "Server should have received a Ping command")
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "Connection should have been closed")
+	}
+}
+
+func TestClient_Heartbeat_Disabled(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeartbeatCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	cl.SetHeartbeatInterval(0)
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		assert.Fail(t, "No command should have been sent", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_Heartbeat_NotSupported(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	cl.SetHeartbeatInterval(10 * time.Millisecond)
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		assert.Fail(t, "No command should have been sent", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_PingReceived(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	ping := command.NewPing()
+	go tcpClient.callOnPayload(pdu.Marshal(ping))
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		_, isPong := cmd.(*command.Pong)
+		assert.True(t, isPong, "Server should have received a Pong command")
+		assert.Equal(t, ping.TransactionID(), cmd.TransactionID())
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "Server should have received a Pong command")
+	}
+}
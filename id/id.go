@@ -1,35 +1,93 @@
 package id
 
 import (
-	"math/rand"
+	"crypto/rand"
 	"strings"
-	"sync"
-	"time"
-)
-
-var (
-	generator = rand.New(rand.NewSource(time.Now().Unix()))
-	mtx       = sync.Mutex{}
+	"sync/atomic"
 )
 
 const (
 	validCharacters = "abcdefghijklmnopqrstuvwxyz0123456789"
 	nbPossibleChars = 36
 	idLength        = 8
+	// nbPossibleIDs is nbPossibleChars^idLength.
+	nbPossibleIDs = 2_821_109_907_456
+	// unbiasedByteLimit is the greatest multiple of nbPossibleChars fitting in a byte,
+	// the random bytes above are discarded to keep every character equally likely.
+	unbiasedByteLimit = 256 - 256%nbPossibleChars
 )
 
-// New generates an id made of 8 bytes.
+// Generator generates ids made of 8 bytes.
+// It must be safe for concurrent use.
+type Generator interface {
+	New() string
+}
+
+var defaultGenerator = NewCryptoGenerator()
+
+// New generates an id made of 8 bytes, using a CryptoGenerator.
 // There is 2.821.109.907.456 possible ids.
 func New() string {
-	mtx.Lock()
-	defer mtx.Unlock()
+	return defaultGenerator.New()
+}
+
+// CryptoGenerator generates random ids from a cryptographically secure source,
+// so independent generators (i.e. started at the same time) don't share their sequence.
+type CryptoGenerator struct{}
+
+func NewCryptoGenerator() *CryptoGenerator {
+	return &CryptoGenerator{}
+}
+
+func (g *CryptoGenerator) New() string {
 	builder := strings.Builder{}
-	for i := 0; i < idLength; i++ {
-		builder.WriteByte(validCharacters[generator.Intn(nbPossibleChars)])
+	builder.Grow(idLength)
+	buf := make([]byte, idLength)
+	for builder.Len() < idLength {
+		_, _ = rand.Read(buf) // Never returns an error
+		for _, b := range buf {
+			if b >= unbiasedByteLimit || builder.Len() == idLength {
+				continue
+			}
+			builder.WriteByte(validCharacters[int(b)%nbPossibleChars])
+		}
 	}
 	return builder.String()
 }
 
+// MonotonicGenerator generates sequential ids, starting from a random one.
+// An id is only reused after the 2.821.109.907.456 others, suited to the transactions of a connection.
+type MonotonicGenerator struct {
+	next atomic.Uint64
+}
+
+func NewMonotonicGenerator() *MonotonicGenerator {
+	g := &MonotonicGenerator{}
+	g.next.Store(decode(New()))
+	return g
+}
+
+func (g *MonotonicGenerator) New() string {
+	return encode((g.next.Add(1) - 1) % nbPossibleIDs)
+}
+
+func encode(value uint64) string {
+	buf := make([]byte, idLength)
+	for i := idLength - 1; i >= 0; i-- {
+		buf[i] = validCharacters[value%nbPossibleChars]
+		value /= nbPossibleChars
+	}
+	return string(buf)
+}
+
+func decode(id string) uint64 {
+	var value uint64
+	for i := 0; i < len(id); i++ {
+		value = value*nbPossibleChars + uint64(strings.IndexByte(validCharacters, id[i]))
+	}
+	return value
+}
+
 // IsValid determines if the given id is a valid one.
 func IsValid(id string) bool {
 	if len(id) != idLength {
@@ -18,6 +18,73 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestCryptoGenerator_New(t *testing.T) {
+	generator := NewCryptoGenerator()
+	otherGenerator := NewCryptoGenerator()
+
+	ids := make(map[string]struct{})
+	for i := 0; i < 1000; i++ {
+		id := generator.New()
+		assert.True(t, IsValid(id))
+		ids[id] = struct{}{}
+		ids[otherGenerator.New()] = struct{}{}
+	}
+	assert.Len(t, ids, 2000, "Generators started together should not share their sequence")
+}
+
+func TestMonotonicGenerator_New(t *testing.T) {
+	generator := NewMonotonicGenerator()
+
+	previous := decode(generator.New())
+	for i := 0; i < 1000; i++ {
+		id := generator.New()
+		assert.True(t, IsValid(id))
+		current := decode(id)
+		assert.Equal(t, (previous+1)%nbPossibleIDs, current)
+		previous = current
+	}
+}
+
+func TestMonotonicGenerator_New_Wraps(t *testing.T) {
+	generator := NewMonotonicGenerator()
+	generator.next.Store(nbPossibleIDs - 1)
+
+	assert.Equal(t, "99999999", generator.New())
+	assert.Equal(t, "aaaaaaaa", generator.New())
+	assert.Equal(t, "aaaaaaab", generator.New())
+}
+
+func TestMonotonicGenerator_New_Concurrent(t *testing.T) {
+	generator := NewMonotonicGenerator()
+
+	concurrentProcesses := 1000
+	ids := make(chan string, concurrentProcesses)
+	wg := sync.WaitGroup{}
+	wg.Add(concurrentProcesses)
+	for i := 0; i < concurrentProcesses; i++ {
+		go func() {
+			defer wg.Done()
+			ids <- generator.New()
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	unique := make(map[string]struct{})
+	for id := range ids {
+		unique[id] = struct{}{}
+	}
+	assert.Len(t, unique, concurrentProcesses)
+}
+
+func TestEncode_Decode(t *testing.T) {
+	for _, value := range []uint64{0, 1, 35, 36, 123456789, nbPossibleIDs - 1} {
+		id := encode(value)
+		assert.True(t, IsValid(id))
+		assert.Equal(t, value, decode(id))
+	}
+}
+
 func TestIsValid(t *testing.T) {
 	for name, tc := range map[string]struct {
 		id      string
@@ -0,0 +1,90 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+type fixedGenerator string
+
+func (g fixedGenerator) New() string { return string(g) }
+
+func TestClient_SetIDGenerator(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	cl.SetIDGenerator(fixedGenerator("abcd1234"))
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		assert.Equal(t, "abcd1234", cmd.TransactionID())
+		return ack(cmd)
+	})
+
+	err = cl.CreateBTunnel("MyTunnel")
+	require.NoError(t, err)
+}
+
+func TestClient_SetIDGenerator_InvalidID(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	cl.SetIDGenerator(fixedGenerator("too-long-id"))
+
+	err = cl.CreateBTunnel("MyTunnel")
+	assert.EqualError(t, err, `validate command: invalid transaction id "too-long-id"`)
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		assert.Fail(t, "No command should have been sent", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_DuplicateTransactionID(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	cl.SetIDGenerator(fixedGenerator("abcd1234"))
+
+	pending := make(chan error)
+	go func() {
+		pending <- cl.CreateBTunnel("MyTunnel")
+	}()
+
+	var first command.Command
+	select {
+	case first = <-tcpClient.commandsChan():
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "Server should have received a CreateTunnel command")
+	}
+
+	// The first transaction is still pending.
+	err = cl.CreateBTunnel("OtherTunnel")
+	assert.ErrorIs(t, err, ErrDuplicateTransactionID)
+	assert.EqualError(t, err, "transaction id already pending: abcd1234")
+
+	replyNext(t, tcpClient, ack)
+	go tcpClient.callOnPayload(tcpClient.framing.Marshal(ack(first)))
+	require.NoError(t, <-pending)
+
+	// Reusable once the first transaction is done.
+	err = cl.CreateBTunnel("OtherTunnel")
+	require.NoError(t, err)
+}
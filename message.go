@@ -0,0 +1,23 @@
+package tunnel
+
+import "github.com/codingLayce/tunnel.go/pdu/command"
+
+// Message is a message received from or published to a Tunnel.
+type Message struct {
+	TunnelName string
+	// ID identifies the message from the publisher to the listeners (i.e. for deduplication).
+	// Generated when publishing, unless already set. Empty if the server doesn't support the message ids.
+	ID string
+	// Headers are the metadata sent along the message by the publisher. Can be nil.
+	Headers map[string]string
+	Body    []byte
+}
+
+func newMessage(cmd *command.ReceiveMessage) *Message {
+	return &Message{
+		TunnelName: cmd.TunnelName,
+		ID:         cmd.MessageID,
+		Headers:    cmd.Headers,
+		Body:       cmd.Message,
+	}
+}
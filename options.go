@@ -0,0 +1,158 @@
+package tunnel
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/codingLayce/tunnel.go/id"
+	"github.com/codingLayce/tunnel.go/tcp"
+)
+
+const (
+	defaultAckTimeout = 10 * time.Second
+	// defaultRequestTimeout is applied to the requests whose context has no deadline.
+	defaultRequestTimeout = 30 * time.Second
+	// defaultReadTimeout is the allowed idle duration before disconnecting from the server, the heartbeats keep
+	// an idle connection alive.
+	defaultReadTimeout = time.Minute
+	// defaultStopTimeout bounds the deletion of the reply tunnel when stopping the Client.
+	defaultStopTimeout = time.Second
+)
+
+// Option configures a Client, see Connect. Connect returns an error matching ErrInvalidOption if a value is invalid.
+type Option func(c *Client)
+
+// Hooks are invoked on the connection events of the Client. Any of them can be nil.
+// They are invoked by the Client's internal routines, so they must not block.
+type Hooks struct {
+	// OnConnected is invoked each time the Client is connected to the server (handshake done), reconnections included.
+	OnConnected func()
+	// OnDisconnected is invoked when the connection with the server is lost, before trying to reconnect.
+	OnDisconnected func()
+	// OnStateChange is invoked on each connection state transition, including the ones of Connect (see Client.OnStateChange).
+	OnStateChange func(StateChange)
+	// OnRestoreError is invoked when a Tunnel cannot be created again or listened again after a reconnection.
+	// A Subscription refused by the server is ended with the error.
+	OnRestoreError func(tunnelName string, err error)
+}
+
+// WithLogger sets the logger of the Client, it cannot be nil. Default slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.Logger = nil
+		if logger != nil {
+			c.Logger = logger.With("entity", "TUNNEL_CLIENT")
+		}
+	}
+}
+
+// WithAckTimeout sets how long the Client waits for the server's response to a command, it must be positive.
+// Default 10 seconds.
+func WithAckTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.ackTimeout = timeout
+	}
+}
+
+// WithRequestTimeout sets the timeout of the requests whose context has no deadline (see Request), it must be positive.
+// Default 30 seconds.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithReadTimeout sets the allowed idle duration before disconnecting from the server. Default 1 minute.
+// It must be greater than 1 second and than the heartbeat interval.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.readTimeout = timeout
+	}
+}
+
+// WithStopTimeout sets how long Stop waits for the server to delete the private Tunnel receiving the replies
+// (see Request), it must be positive. Default 1 second.
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.stopTimeout = timeout
+	}
+}
+
+// WithDialer sets the dialer opening the connections to the server (i.e. a *net.Dialer, a proxy or a TLS dialer).
+func WithDialer(dialer tcp.Dialer) Option {
+	return func(c *Client) {
+		c.dialer = dialer
+	}
+}
+
+// WithRetryPolicy sets the delays between the reconnection attempts and when to give up (see MaxAttempts).
+// Default the delay starts at 1 second, increasing by 20% after each attempt with a 20% jitter, never giving up.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithWaitForReconnect makes the operations wait for the Client to be connected again (handshake done and Tunnels
+// restored) while reconnecting, instead of failing with ErrNotConnected. The wait is bounded by the operation's context,
+// use the Context variants with a deadline to bound it. Default false.
+func WithWaitForReconnect(wait bool) Option {
+	return func(c *Client) {
+		c.waitForReconnect = wait
+	}
+}
+
+// WithClientName sets the name sent to the server during the handshake, i.e. to identify the client in the server logs.
+// Up to 64 letters, digits, '_', '-' or '.'.
+func WithClientName(name string) Option {
+	return func(c *Client) {
+		c.name = name
+	}
+}
+
+// WithHooks sets the hooks invoked on the connection events of the Client.
+func WithHooks(hooks Hooks) Option {
+	return func(c *Client) {
+		c.hooks = hooks
+	}
+}
+
+// WithHeartbeatInterval sets the delay between two heartbeats (see SetHeartbeatInterval). Default 20 seconds.
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.heartbeatInterval = interval
+	}
+}
+
+// WithIDGenerator sets the generator of the transaction ids (see SetIDGenerator).
+func WithIDGenerator(generator id.Generator) Option {
+	return func(c *Client) {
+		c.ids = generator
+	}
+}
+
+// WithCompressionThreshold sets the minimum data size of the compressed commands (see SetCompressionThreshold).
+// Default 1KiB.
+func WithCompressionThreshold(threshold int) Option {
+	return func(c *Client) {
+		c.compressionThreshold = threshold
+	}
+}
+
+func (c *Client) validate() error {
+	// The Options cannot fail, their values are checked once applied.
+	switch {
+	case c.Logger == nil:
+		return fmt.Errorf("%w: nil logger", ErrInvalidOption)
+	case c.ackTimeout <= 0:
+		return fmt.Errorf("%w: ack timeout %s must be positive", ErrInvalidOption, c.ackTimeout)
+	case c.requestTimeout <= 0:
+		return fmt.Errorf("%w: request timeout %s must be positive", ErrInvalidOption, c.requestTimeout)
+	case c.readTimeout <= time.Second:
+		return fmt.Errorf("%w: read timeout %s must be greater than 1s", ErrInvalidOption, c.readTimeout)
+	case c.stopTimeout <= 0:
+		return fmt.Errorf("%w: stop timeout %s must be positive", ErrInvalidOption, c.stopTimeout)
+	}
+	return nil
+}
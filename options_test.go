@@ -0,0 +1,196 @@
+package tunnel
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestConnect_Defaults(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("addr")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	assert.Equal(t, defaultAckTimeout, cl.ackTimeout)
+	assert.Equal(t, defaultRequestTimeout, cl.requestTimeout)
+	assert.Equal(t, defaultStopTimeout, cl.stopTimeout)
+	assert.Equal(t, defaultHeartbeatInterval, cl.heartbeatInterval)
+	assert.Equal(t, defaultCompressionThreshold, cl.compressionThreshold)
+	assert.Equal(t, "addr", tcpClient.options().Addr)
+	assert.Equal(t, defaultReadTimeout, tcpClient.options().ReadTimeout)
+	assert.Nil(t, tcpClient.options().Dialer)
+}
+
+func TestConnect_Options(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	var offer *command.Hello
+	tcpClient.hello = func(cmd *command.Hello) command.Command {
+		offer = cmd
+		return command.NewHelloWithTransactionID(cmd.TransactionID())
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	logs := bytes.Buffer{}
+	dialer := &net.Dialer{Timeout: time.Second}
+	cl, err := Connect("addr",
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithAckTimeout(time.Second),
+		WithRequestTimeout(2*time.Second),
+		WithReadTimeout(3*time.Second),
+		WithStopTimeout(5*time.Second),
+		WithDialer(dialer),
+		WithClientName("billing-service"),
+		WithHeartbeatInterval(4*time.Second),
+		WithCompressionThreshold(512),
+	)
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	assert.Equal(t, time.Second, cl.ackTimeout)
+	assert.Equal(t, 2*time.Second, cl.requestTimeout)
+	assert.Equal(t, 5*time.Second, cl.stopTimeout)
+	assert.Equal(t, 4*time.Second, cl.heartbeatInterval)
+	assert.Equal(t, 512, cl.compressionThreshold)
+	assert.Equal(t, 3*time.Second, tcpClient.options().ReadTimeout)
+	assert.Same(t, dialer, tcpClient.options().Dialer)
+	require.NotNil(t, offer)
+	assert.Equal(t, "billing-service", offer.Name)
+	assert.Contains(t, logs.String(), "msg=\"Connected to Tunnel server\" entity=TUNNEL_CLIENT")
+}
+
+func TestConnect_InvalidOption(t *testing.T) {
+	for name, tc := range map[string]struct {
+		option               Option
+		expectedErrorMessage string
+	}{
+		"Nil logger": {
+			option:               WithLogger(nil),
+			expectedErrorMessage: "connect to Tunnel server: invalid option: nil logger",
+		},
+		"Zero ack timeout": {
+			option:               WithAckTimeout(0),
+			expectedErrorMessage: "connect to Tunnel server: invalid option: ack timeout 0s must be positive",
+		},
+		"Negative request timeout": {
+			option:               WithRequestTimeout(-time.Second),
+			expectedErrorMessage: "connect to Tunnel server: invalid option: request timeout -1s must be positive",
+		},
+		"Short read timeout": {
+			option:               WithReadTimeout(time.Second),
+			expectedErrorMessage: "connect to Tunnel server: invalid option: read timeout 1s must be greater than 1s",
+		},
+		"Zero stop timeout": {
+			option:               WithStopTimeout(0),
+			expectedErrorMessage: "connect to Tunnel server: invalid option: stop timeout 0s must be positive",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tcpClient := newTestTCPClient()
+			tcpClient.connect = func() error {
+				assert.Fail(t, "The client shouldn't connect")
+				return nil
+			}
+			mockNewTCPClient(t, tcpClient)
+
+			_, err := Connect("", tc.option)
+			assert.ErrorIs(t, err, ErrInvalidOption)
+			assert.EqualError(t, err, tc.expectedErrorMessage)
+		})
+	}
+}
+
+func TestConnect_InvalidClientName(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	_, err := Connect("", WithClientName("invalid name"))
+	assert.EqualError(t, err, "connect to Tunnel server: handshake: validate command: invalid name")
+}
+
+func TestConnect_AckTimeoutPerClient(t *testing.T) {
+	var clients []*Client
+	for _, timeout := range []time.Duration{50 * time.Millisecond, time.Second} {
+		tcpClient := newTestTCPClient()
+		mockNewTCPClient(t, tcpClient)
+		cl, err := Connect("", WithAckTimeout(timeout))
+		require.NoError(t, err)
+		defer cl.Stop()
+		clients = append(clients, cl)
+
+		go func() {
+			<-tcpClient.commandsChan() // Never responding
+		}()
+	}
+
+	start := time.Now()
+	err := clients[0].CreateBTunnel("MyTunnel")
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestConnect_HooksAndRetryPolicy(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	failures := atomic.Int32{}
+	tcpClient.connect = func() error {
+		if failures.Load() > 0 {
+			failures.Add(-1)
+			return errors.New("unreachable")
+		}
+		return nil
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	var attempts []int
+	var events []string
+	mtx := sync.Mutex{}
+	cl, err := Connect("",
+		WithRetryPolicy(RetryPolicyFunc(func(attempt int) (time.Duration, bool) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			attempts = append(attempts, attempt)
+			return time.Millisecond, true
+		})),
+		WithHooks(Hooks{
+			OnConnected: func() {
+				mtx.Lock()
+				defer mtx.Unlock()
+				events = append(events, "connected")
+			},
+			OnDisconnected: func() {
+				mtx.Lock()
+				defer mtx.Unlock()
+				events = append(events, "disconnected")
+			},
+		}),
+	)
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	// Lose the connection, the 2 first reconnection attempts fail
+	failures.Store(2)
+	tcpClient.disconnect()
+
+	assert.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(events) == 3
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, []string{"connected", "disconnected", "connected"}, events)
+	assert.Equal(t, []int{1, 2}, attempts)
+}
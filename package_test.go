@@ -2,7 +2,12 @@ package tunnel
 
 import (
 	"log/slog"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/codingLayce/tunnel.go/pdu"
 	"github.com/codingLayce/tunnel.go/pdu/command"
@@ -11,25 +16,56 @@ import (
 )
 
 type TestTCPClient struct {
-	connect   func() error
+	connect func() error
+	stop    func()
+	send    func([]byte) error
+	cmdCh   chan command.Command
+	framing pdu.Framing
+
+	// mtx guards done, renewed on each connection, as well as onPayload and opts, set each time the client
+	// creates a new internal client.
+	mtx       sync.Mutex
 	done      chan struct{}
 	onPayload func([]byte)
-	send      func([]byte) error
-	cmdCh     chan command.Command
+	// opts are the options the TestTCPClient has been created with.
+	opts *tcp.ClientOption
+	// compression decompresses the compressed commands sent by the client.
+	compression pdu.Compression
+
+	// hello replies to the handshake. By default, the handshake is accepted without any capability.
+	hello func(offer *command.Hello) command.Command
 }
 
 func newTestTCPClient() *TestTCPClient {
 	return &TestTCPClient{done: make(chan struct{}), cmdCh: make(chan command.Command)}
 }
 
+// newTestTCPClientWithCapabilities returns a TestTCPClient agreeing the capabilities during the handshake.
+// The commands sent by the client are decompressed following the agreed compression.
+func newTestTCPClientWithCapabilities(capabilities ...command.Capability) *TestTCPClient {
+	tcpClient := newTestTCPClient()
+	tcpClient.compression = pdu.NegotiatedCompression(capabilities)
+	tcpClient.hello = func(offer *command.Hello) command.Command {
+		hello := command.NewHelloWithTransactionID(offer.TransactionID())
+		hello.Capabilities = capabilities
+		return hello
+	}
+	return tcpClient
+}
+
 func (t *TestTCPClient) Connect() error {
+	t.mtx.Lock()
 	t.done = make(chan struct{})
+	t.mtx.Unlock()
 	if t.connect != nil {
 		return t.connect()
 	}
 	return nil
 }
 func (t *TestTCPClient) Stop() {
+	if t.stop != nil {
+		t.stop()
+	}
 	if t.cmdCh != nil {
 		select {
 		case <-t.cmdCh:
@@ -38,44 +74,126 @@ func (t *TestTCPClient) Stop() {
 		}
 	}
 }
-func (t *TestTCPClient) Done() <-chan struct{} { return t.done }
+func (t *TestTCPClient) Done() <-chan struct{} {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.done
+}
+
+func (t *TestTCPClient) SetFraming(framing pdu.Framing) { t.framing = framing }
 func (t *TestTCPClient) Send(payload []byte) error {
+	cmd, err := t.framing.UnmarshalCompressed(payload, t.compression, 0)
+	if err == nil {
+		if offer, ok := cmd.(*command.Hello); ok {
+			t.replyHello(offer)
+			return nil
+		}
+	}
+
 	if t.send != nil {
 		return t.send(payload)
 	}
 
-	cmd, err := pdu.Unmarshal(payload)
 	if err != nil {
 		return err
 	}
 
 	select {
 	case t.cmdCh <- cmd:
-	case <-t.done:
+	case <-t.Done():
 	}
 
 	return nil
 }
 
+func (t *TestTCPClient) replyHello(offer *command.Hello) {
+	var response command.Command = command.NewHelloWithTransactionID(offer.TransactionID())
+	if t.hello != nil {
+		response = t.hello(offer)
+	}
+	if response != nil {
+		t.callOnPayload(t.framing.Marshal(response))
+	}
+}
+
 func (t *TestTCPClient) callOnPayload(payload []byte) {
-	if t.onPayload != nil {
-		t.onPayload(payload)
+	t.mtx.Lock()
+	onPayload := t.onPayload
+	t.mtx.Unlock()
+	if onPayload != nil {
+		onPayload(payload)
 	}
 }
 
+func (t *TestTCPClient) options() *tcp.ClientOption {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.opts
+}
+
+// disconnect simulates the loss of the connection.
+func (t *TestTCPClient) disconnect() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	close(t.done)
+}
+
 func (t *TestTCPClient) commandsChan() <-chan command.Command {
 	return t.cmdCh
 }
 
 func mockNewTCPClient(t *testing.T, client *TestTCPClient) {
 	mock.Do(t, &newTCPClient, func(opts *tcp.ClientOption) TCPClient {
+		client.mtx.Lock()
+		defer client.mtx.Unlock()
 		client.onPayload = opts.OnPayload
+		client.opts = opts
 		return client
 	})
 }
 
+// replyNext responds to the next command sent by the client.
+func replyNext(t *testing.T, tcpClient *TestTCPClient, response func(cmd command.Command) command.Command) {
+	go func() {
+		select {
+		case cmd := <-tcpClient.commandsChan():
+			tcpClient.callOnPayload(pdu.Marshal(response(cmd)))
+		case <-time.After(100 * time.Millisecond):
+			assert.Fail(t, "Server should have received a command")
+		}
+	}()
+}
+
+func ack(cmd command.Command) command.Command {
+	return command.NewAckWithTransactionID(cmd.TransactionID())
+}
+
+// receiveCommand waits for the next command sent by the client.
+func receiveCommand(t *testing.T, tcpClient *TestTCPClient) command.Command {
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		return cmd
+	case <-time.After(time.Second):
+		require.FailNow(t, "Server should have received a command")
+		return nil
+	}
+}
+
+func nack(code command.NackCode) func(cmd command.Command) command.Command {
+	return func(cmd command.Command) command.Command {
+		nack := command.NewNackWithTransactionID(cmd.TransactionID())
+		nack.Code = code
+		return nack
+	}
+}
+
 func TestMain(m *testing.M) {
 	slog.SetLogLoggerLevel(slog.LevelInfo)
 
+	err := command.Register(customIndicator, parseCustomCommand)
+	if err != nil {
+		panic(err)
+	}
+
 	m.Run()
 }
@@ -1,12 +1,54 @@
 package command
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
 
 const (
 	ackData  = "OK"
 	nackData = "KO"
 )
 
+// NackCode indicates why a command has not been acknowledged.
+type NackCode uint16
+
+const (
+	NackUnknown NackCode = iota
+	NackMalformedCommand
+	NackUnsupportedCommand
+	NackTunnelNotFound
+	NackTunnelExists
+	NackNotAuthorized
+	NackInternalError
+	// NackRequeue is sent by a listener which couldn't process the message now, asking for it to be delivered again.
+	NackRequeue
+)
+
+func (code NackCode) String() string {
+	switch code {
+	case NackUnknown:
+		return "unknown"
+	case NackMalformedCommand:
+		return "malformed_command"
+	case NackUnsupportedCommand:
+		return "unsupported_command"
+	case NackTunnelNotFound:
+		return "tunnel_not_found"
+	case NackTunnelExists:
+		return "tunnel_exists"
+	case NackNotAuthorized:
+		return "not_authorized"
+	case NackInternalError:
+		return "internal_error"
+	case NackRequeue:
+		return "requeue"
+	default:
+		return fmt.Sprintf("code_%d", uint16(code))
+	}
+}
+
 type (
 	// Ack represents a valid acknowledgement.
 	Ack struct {
@@ -14,18 +56,28 @@ type (
 	}
 
 	// Nack represents a non acknowledgement.
+	// Code and Reason are optional, a bare nack has the NackUnknown code and no reason.
 	Nack struct {
 		transactionID string
+
+		Code   NackCode
+		Reason string
 	}
 )
 
 func parseAcknowledgement(transactionID string, data []byte) (Command, error) {
 	var cmd Command
-	switch string(data) {
-	case ackData:
+	switch {
+	case string(data) == ackData:
 		cmd = NewAckWithTransactionID(transactionID)
-	case nackData:
+	case string(data) == nackData:
 		cmd = NewNackWithTransactionID(transactionID)
+	case bytes.HasPrefix(data, []byte(nackData+" ")):
+		nack, err := parseNack(transactionID, data[len(nackData)+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid acknowledgement command: %s", err)
+		}
+		cmd = nack
 	default:
 		return nil, fmt.Errorf("invalid acknowledgement command: unknown data %q", string(data))
 	}
@@ -34,6 +86,20 @@ func parseAcknowledgement(transactionID string, data []byte) (Command, error) {
 	return cmd, nil
 }
 
+func parseNack(transactionID string, data []byte) (*Nack, error) {
+	// data is "<code>[ <reason>]"
+	rawCode, reason, _ := bytes.Cut(data, []byte(" "))
+	code, err := strconv.ParseUint(string(rawCode), 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nack code %q", string(rawCode))
+	}
+
+	cmd := NewNackWithTransactionID(transactionID)
+	cmd.Code = NackCode(code)
+	cmd.Reason = string(reason)
+	return cmd, nil
+}
+
 func NewAck() *Ack { return &Ack{transactionID: newID()} }
 func NewAckWithTransactionID(transactionID string) *Ack {
 	cmd := NewAck()
@@ -52,8 +118,27 @@ func NewNackWithTransactionID(transactionID string) *Nack {
 	cmd.transactionID = transactionID
 	return cmd
 }
-func (nack *Nack) Validate() error       { return nil }
-func (nack *Nack) Info() string          { return "NACK" }
+func (nack *Nack) Validate() error { return nil }
+func (nack *Nack) Info() string {
+	if nack.Code == NackUnknown {
+		return "NACK"
+	}
+	return fmt.Sprintf("NACK(%s)", nack.Code)
+}
 func (nack *Nack) TransactionID() string { return nack.transactionID }
 func (nack *Nack) Indicator() byte       { return AcknowledgementIndicator }
-func (nack *Nack) Data() []byte          { return []byte(nackData) }
+func (nack *Nack) Data() []byte {
+	if nack.Code == NackUnknown && nack.Reason == "" {
+		return []byte(nackData)
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString(nackData)
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(int(nack.Code)))
+	if nack.Reason != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(nack.Reason)
+	}
+	return buf.Bytes()
+}
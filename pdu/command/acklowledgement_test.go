@@ -37,3 +37,24 @@ func TestNack_Indicator(t *testing.T) {
 func TestNack_Data(t *testing.T) {
 	assert.Equal(t, []byte(nackData), NewNack().Data())
 }
+
+func TestNack_Info_WithCode(t *testing.T) {
+	nack := NewNack()
+	nack.Code = NackTunnelNotFound
+	assert.Equal(t, "NACK(tunnel_not_found)", nack.Info())
+}
+
+func TestNack_Data_WithCode(t *testing.T) {
+	nack := NewNack()
+	nack.Code = NackTunnelExists
+	assert.Equal(t, []byte("KO 4"), nack.Data())
+
+	nack.Reason = "Tunnel MyTunnel already exists"
+	assert.Equal(t, []byte("KO 4 Tunnel MyTunnel already exists"), nack.Data())
+}
+
+func TestNackCode_String(t *testing.T) {
+	assert.Equal(t, "not_authorized", NackNotAuthorized.String())
+	assert.Equal(t, "requeue", NackRequeue.String())
+	assert.Equal(t, "code_999", NackCode(999).String())
+}
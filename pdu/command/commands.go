@@ -1,10 +1,6 @@
 package command
 
-import (
-	"fmt"
-
-	"github.com/codingLayce/tunnel.go/id"
-)
+import "github.com/codingLayce/tunnel.go/id"
 
 const (
 	AcknowledgementIndicator byte = '@'
@@ -12,6 +8,13 @@ const (
 	ListenTunnelIndicator    byte = '#'
 	PublishMessageIndicator  byte = '>'
 	ReceiveMessageIndicator  byte = '<'
+	HelloIndicator           byte = '!'
+	UnlistenTunnelIndicator  byte = '~'
+	DeleteTunnelIndicator    byte = '-'
+	ListTunnelsIndicator     byte = '?'
+	DescribeTunnelIndicator  byte = '='
+	TunnelInfoIndicator      byte = '%'
+	HeartbeatIndicator       byte = '*'
 )
 
 type Command interface {
@@ -22,23 +25,10 @@ type Command interface {
 	Data() []byte
 }
 
-// Parse to the corresponding command.
+// Parse to the corresponding command using the DefaultRegistry.
 // Returns an error if no command fit the given attributes or if it's invalid.
 func Parse(indicator byte, transactionID string, data []byte) (Command, error) {
-	switch indicator {
-	case AcknowledgementIndicator:
-		return parseAcknowledgement(transactionID, data)
-	case CreateTunnelIndicator:
-		return parseCreateTunnel(transactionID, data)
-	case ListenTunnelIndicator:
-		return parseListenTunnel(transactionID, data)
-	case PublishMessageIndicator:
-		return parsePublishMessage(transactionID, data)
-	case ReceiveMessageIndicator:
-		return parseReceiveMessage(transactionID, data)
-	default:
-		return nil, fmt.Errorf("invalid command indicator: unknown 0x%x", indicator)
-	}
+	return DefaultRegistry.Parse(indicator, transactionID, data)
 }
 
 var newID = id.New
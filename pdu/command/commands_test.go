@@ -24,25 +24,182 @@ func TestParse(t *testing.T) {
 			data:            []byte(nackData),
 			expectedCommand: NewNackWithTransactionID(transactionID),
 		},
+		"Nack with code": {
+			indicator:       AcknowledgementIndicator,
+			data:            []byte("KO 3"),
+			expectedCommand: &Nack{transactionID: transactionID, Code: NackTunnelNotFound},
+		},
+		"Nack with code and reason": {
+			indicator:       AcknowledgementIndicator,
+			data:            []byte("KO 3 Tunnel MyTunnel doesn't exist"),
+			expectedCommand: &Nack{transactionID: transactionID, Code: NackTunnelNotFound, Reason: "Tunnel MyTunnel doesn't exist"},
+		},
 		"Create Tunnel": {
 			indicator:       CreateTunnelIndicator,
 			data:            data([]byte{0}, []byte("Bidule17")),
 			expectedCommand: NewCreateTunnelWithTransactionID(transactionID, "Bidule17"),
 		},
+		"Create Queue Tunnel": {
+			indicator: CreateTunnelIndicator,
+			data:      data([]byte{1}, []byte("Bidule17")),
+			expectedCommand: func() Command {
+				cmd := NewCreateTunnelWithTransactionID(transactionID, "Bidule17")
+				cmd.Type = QueueTunnel
+				return cmd
+			}(),
+		},
+		"Create Topic Tunnel": {
+			indicator: CreateTunnelIndicator,
+			data:      data([]byte{2}, []byte("orders.eu.created")),
+			expectedCommand: func() Command {
+				cmd := NewCreateTunnelWithTransactionID(transactionID, "orders.eu.created")
+				cmd.Type = TopicTunnel
+				return cmd
+			}(),
+		},
+		"Listen Tunnel pattern": {
+			indicator:       ListenTunnelIndicator,
+			data:            []byte("orders.*.>"),
+			expectedCommand: NewListenTunnelWithTransactionID(transactionID, "orders.*.>"),
+		},
+		"Unlisten Tunnel pattern": {
+			indicator:       UnlistenTunnelIndicator,
+			data:            []byte("orders.*.>"),
+			expectedCommand: NewUnlistenTunnelWithTransactionID(transactionID, "orders.*.>"),
+		},
 		"Listen Tunnel": {
 			indicator:       ListenTunnelIndicator,
 			data:            []byte("Bidule"),
 			expectedCommand: NewListenTunnelWithTransactionID(transactionID, "Bidule"),
 		},
+		"Unlisten Tunnel": {
+			indicator:       UnlistenTunnelIndicator,
+			data:            []byte("Bidule"),
+			expectedCommand: NewUnlistenTunnelWithTransactionID(transactionID, "Bidule"),
+		},
+		"Delete Tunnel": {
+			indicator:       DeleteTunnelIndicator,
+			data:            []byte("Bidule"),
+			expectedCommand: NewDeleteTunnelWithTransactionID(transactionID, "Bidule"),
+		},
+		"List Tunnels": {
+			indicator:       ListTunnelsIndicator,
+			data:            []byte(""),
+			expectedCommand: NewListTunnelsWithTransactionID(transactionID, ""),
+		},
+		"List Tunnels with pattern": {
+			indicator:       ListTunnelsIndicator,
+			data:            []byte("Bid*"),
+			expectedCommand: NewListTunnelsWithTransactionID(transactionID, "Bid*"),
+		},
+		"Describe Tunnel": {
+			indicator:       DescribeTunnelIndicator,
+			data:            []byte("Bidule"),
+			expectedCommand: NewDescribeTunnelWithTransactionID(transactionID, "Bidule"),
+		},
+		"Tunnel Info": {
+			indicator: TunnelInfoIndicator,
+			data:      []byte("Bidule:0:3 Machin:0:0"),
+			expectedCommand: NewTunnelInfoWithTransactionID(transactionID,
+				TunnelDescription{Name: "Bidule", Type: BroadcastTunnel, Listeners: 3},
+				TunnelDescription{Name: "Machin", Type: BroadcastTunnel},
+			),
+		},
+		"Ping": {
+			indicator:       HeartbeatIndicator,
+			data:            []byte(pingData),
+			expectedCommand: NewPingWithTransactionID(transactionID),
+		},
+		"Pong": {
+			indicator:       HeartbeatIndicator,
+			data:            []byte(pongData),
+			expectedCommand: NewPongWithTransactionID(transactionID),
+		},
+		"Tunnel Info without tunnels": {
+			indicator:       TunnelInfoIndicator,
+			data:            []byte(""),
+			expectedCommand: NewTunnelInfoWithTransactionID(transactionID),
+		},
 		"Publish Message": {
 			indicator:       PublishMessageIndicator,
 			data:            data([]byte("TunnelName"), []byte{' '}, []byte("Mon super message")),
-			expectedCommand: NewPublishMessageWithTransactionID(transactionID, "TunnelName", "Mon super message"),
+			expectedCommand: NewPublishMessageWithTransactionID(transactionID, "TunnelName", []byte("Mon super message")),
 		},
 		"Receive Message": {
 			indicator:       ReceiveMessageIndicator,
 			data:            data([]byte("TunnelName"), []byte{' '}, []byte("Mon super message")),
-			expectedCommand: NewReceiveMessageWithTransactionID(transactionID, "TunnelName", "Mon super message"),
+			expectedCommand: NewReceiveMessageWithTransactionID(transactionID, "TunnelName", []byte("Mon super message")),
+		},
+		"Publish Message - Binary": {
+			indicator:       PublishMessageIndicator,
+			data:            data([]byte("TunnelName"), []byte{' '}, []byte("{\"été\": [1, 2]}\n"), []byte{0x00, 0xff}),
+			expectedCommand: NewPublishMessageWithTransactionID(transactionID, "TunnelName", data([]byte("{\"été\": [1, 2]}\n"), []byte{0x00, 0xff})),
+		},
+		"Receive Message - Binary": {
+			indicator:       ReceiveMessageIndicator,
+			data:            data([]byte("TunnelName"), []byte{' '}, []byte("{\"été\": [1, 2]}\n"), []byte{0x00, 0xff}),
+			expectedCommand: NewReceiveMessageWithTransactionID(transactionID, "TunnelName", data([]byte("{\"été\": [1, 2]}\n"), []byte{0x00, 0xff})),
+		},
+		"Publish Message - Headers": {
+			indicator: PublishMessageIndicator,
+			data:      []byte("TunnelName?content-type=application%2Fjson&trace+id=a%26b Mon super message"),
+			expectedCommand: &PublishMessage{
+				transactionID: transactionID,
+				TunnelName:    "TunnelName",
+				Headers:       Headers{"content-type": "application/json", "trace id": "a&b"},
+				Message:       []byte("Mon super message"),
+			},
+		},
+		"Receive Message - Headers": {
+			indicator: ReceiveMessageIndicator,
+			data:      []byte("TunnelName?content-type=application%2Fjson&trace+id=a%26b Mon super message"),
+			expectedCommand: &ReceiveMessage{
+				transactionID: transactionID,
+				TunnelName:    "TunnelName",
+				Headers:       Headers{"content-type": "application/json", "trace id": "a&b"},
+				Message:       []byte("Mon super message"),
+			},
+		},
+		"Publish Message - Message ID": {
+			indicator: PublishMessageIndicator,
+			data:      []byte("TunnelName:9m4e2mr0ui3e8a215n4g?trace+id=a%26b Mon super message"),
+			expectedCommand: &PublishMessage{
+				transactionID: transactionID,
+				TunnelName:    "TunnelName",
+				MessageID:     "9m4e2mr0ui3e8a215n4g",
+				Headers:       Headers{"trace id": "a&b"},
+				Message:       []byte("Mon super message"),
+			},
+		},
+		"Receive Message - Message ID": {
+			indicator: ReceiveMessageIndicator,
+			data:      []byte("TunnelName:9m4e2mr0ui3e8a215n4g Mon super message"),
+			expectedCommand: &ReceiveMessage{
+				transactionID: transactionID,
+				TunnelName:    "TunnelName",
+				MessageID:     "9m4e2mr0ui3e8a215n4g",
+				Message:       []byte("Mon super message"),
+			},
+		},
+		"Hello": {
+			indicator:       HelloIndicator,
+			data:            []byte("1 1024"),
+			expectedCommand: &Hello{transactionID: transactionID, Version: 1, MaxFrameSize: 1024},
+		},
+		"Hello with capabilities": {
+			indicator:       HelloIndicator,
+			data:            []byte("1 0 length_prefixed_framing,other"),
+			expectedCommand: &Hello{transactionID: transactionID, Version: 1, Capabilities: []Capability{LengthPrefixedFramingCapability, "other"}},
+		},
+		"Hello with name": {
+			indicator:       HelloIndicator,
+			data:            []byte("1 0 heartbeat billing-service"),
+			expectedCommand: &Hello{transactionID: transactionID, Version: 1, Capabilities: []Capability{HeartbeatCapability}, Name: "billing-service"},
+		},
+		"Hello with name without capabilities": {
+			indicator:       HelloIndicator,
+			data:            []byte("1 0  billing-service"),
+			expectedCommand: &Hello{transactionID: transactionID, Version: 1, Name: "billing-service"},
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -68,6 +225,11 @@ func TestParse_Errors(t *testing.T) {
 			data:             []byte("Bidule"),
 			expectedErrorMsg: `invalid acknowledgement command: unknown data "Bidule"`,
 		},
+		"Acknowledgement invalid payload - Nack code": {
+			indicator:        AcknowledgementIndicator,
+			data:             []byte("KO tunnel_not_found"),
+			expectedErrorMsg: `invalid acknowledgement command: invalid nack code "tunnel_not_found"`,
+		},
 		"Create_tunnel invalid payload": {
 			indicator:        CreateTunnelIndicator,
 			data:             []byte{},
@@ -78,6 +240,16 @@ func TestParse_Errors(t *testing.T) {
 			data:             []byte("Mon_Tunnel"),
 			expectedErrorMsg: `invalid create_tunnel command: invalid type`,
 		},
+		"Create_tunnel invalid validation - Topic Tunnel Name": {
+			indicator:        CreateTunnelIndicator,
+			data:             data([]byte{0x02}, []byte("orders..created")),
+			expectedErrorMsg: `invalid create_tunnel command: invalid name`,
+		},
+		"Listen_tunnel invalid validation - Pattern": {
+			indicator:        ListenTunnelIndicator,
+			data:             []byte("orders.>.created"),
+			expectedErrorMsg: `invalid listen_tunnel command: invalid name`,
+		},
 		"Create_tunnel invalid validation - Tunnel Name": {
 			indicator:        CreateTunnelIndicator,
 			data:             data([]byte{0x00}, []byte("Invalid_Tunn$l")),
@@ -93,6 +265,71 @@ func TestParse_Errors(t *testing.T) {
 			data:             []byte("Mon_Tunn&l"),
 			expectedErrorMsg: `invalid listen_tunnel command: invalid name`,
 		},
+		"Unlisten_tunnel invalid payload": {
+			indicator:        UnlistenTunnelIndicator,
+			data:             []byte(""),
+			expectedErrorMsg: `invalid payload: missing tunnel name`,
+		},
+		"Unlisten_tunnel invalid validation - Tunnel Name": {
+			indicator:        UnlistenTunnelIndicator,
+			data:             []byte("Mon_Tunn&l"),
+			expectedErrorMsg: `invalid unlisten_tunnel command: invalid name`,
+		},
+		"Delete_tunnel invalid payload": {
+			indicator:        DeleteTunnelIndicator,
+			data:             []byte(""),
+			expectedErrorMsg: `invalid payload: missing tunnel name`,
+		},
+		"Delete_tunnel invalid validation - Tunnel Name": {
+			indicator:        DeleteTunnelIndicator,
+			data:             []byte("Mon_Tunn&l"),
+			expectedErrorMsg: `invalid delete_tunnel command: invalid name`,
+		},
+		"List_tunnels invalid validation - Pattern": {
+			indicator:        ListTunnelsIndicator,
+			data:             []byte("Bid?le"),
+			expectedErrorMsg: `invalid list_tunnels command: invalid pattern`,
+		},
+		"Describe_tunnel invalid payload": {
+			indicator:        DescribeTunnelIndicator,
+			data:             []byte(""),
+			expectedErrorMsg: `invalid payload: missing tunnel name`,
+		},
+		"Describe_tunnel invalid validation - Tunnel Name": {
+			indicator:        DescribeTunnelIndicator,
+			data:             []byte("Mon_Tunn&l"),
+			expectedErrorMsg: `invalid describe_tunnel command: invalid name`,
+		},
+		"Heartbeat invalid payload": {
+			indicator:        HeartbeatIndicator,
+			data:             []byte("PANG"),
+			expectedErrorMsg: `invalid heartbeat command: unknown data "PANG"`,
+		},
+		"Tunnel_info invalid payload - Entry": {
+			indicator:        TunnelInfoIndicator,
+			data:             []byte("Bidule:0"),
+			expectedErrorMsg: `invalid payload: invalid tunnel entry "Bidule:0"`,
+		},
+		"Tunnel_info invalid payload - Type": {
+			indicator:        TunnelInfoIndicator,
+			data:             []byte("Bidule:a:0"),
+			expectedErrorMsg: `invalid payload: invalid tunnel type "a"`,
+		},
+		"Tunnel_info invalid payload - Listeners": {
+			indicator:        TunnelInfoIndicator,
+			data:             []byte("Bidule:0:a"),
+			expectedErrorMsg: `invalid payload: invalid listeners count "a"`,
+		},
+		"Tunnel_info invalid validation - Tunnel Name": {
+			indicator:        TunnelInfoIndicator,
+			data:             []byte("Mon_Tunn&l:0:0"),
+			expectedErrorMsg: `invalid tunnel_info command: tunnel "Mon_Tunn&l": invalid name`,
+		},
+		"Tunnel_info invalid validation - Listeners": {
+			indicator:        TunnelInfoIndicator,
+			data:             []byte("Bidule:0:-1"),
+			expectedErrorMsg: `invalid tunnel_info command: tunnel "Bidule": invalid listeners count`,
+		},
 		"Publish_message invalid payload": {
 			indicator:        PublishMessageIndicator,
 			data:             []byte(""),
@@ -103,10 +340,15 @@ func TestParse_Errors(t *testing.T) {
 			data:             []byte("Inval+d_Tunnel Mon super message"),
 			expectedErrorMsg: "invalid publish_message command: invalid tunnel_name",
 		},
-		"Publish_message invalid validation - Message": {
+		"Publish_message invalid payload - Headers": {
+			indicator:        PublishMessageIndicator,
+			data:             []byte("Bidule?key=%zz Mon super message"),
+			expectedErrorMsg: "invalid payload: invalid headers",
+		},
+		"Publish_message invalid validation - Headers": {
 			indicator:        PublishMessageIndicator,
-			data:             []byte("Bidule Invalide message chars &*&*"),
-			expectedErrorMsg: "invalid publish_message command: invalid message",
+			data:             []byte("Bidule?=value Mon super message"),
+			expectedErrorMsg: "invalid publish_message command: invalid headers",
 		},
 		"Receive_message invalid payload": {
 			indicator:        ReceiveMessageIndicator,
@@ -118,10 +360,60 @@ func TestParse_Errors(t *testing.T) {
 			data:             []byte("Invalid&Tunnel Mon super message"),
 			expectedErrorMsg: "invalid receive_message command: invalid tunnel_name",
 		},
-		"Receive_message invalid validation - Message": {
+		"Hello invalid payload - Missing values": {
+			indicator:        HelloIndicator,
+			data:             []byte("1"),
+			expectedErrorMsg: "invalid payload: expected 2 to 4 values, got 1",
+		},
+		"Hello invalid payload - Version": {
+			indicator:        HelloIndicator,
+			data:             []byte("v1 0"),
+			expectedErrorMsg: `invalid payload: invalid version "v1"`,
+		},
+		"Hello invalid payload - Max frame size": {
+			indicator:        HelloIndicator,
+			data:             []byte("1 -1"),
+			expectedErrorMsg: `invalid payload: invalid max frame size "-1"`,
+		},
+		"Hello invalid validation - Version": {
+			indicator:        HelloIndicator,
+			data:             []byte("0 0"),
+			expectedErrorMsg: "invalid hello command: invalid version",
+		},
+		"Hello invalid validation - Capability": {
+			indicator:        HelloIndicator,
+			data:             []byte("1 0 framing,"),
+			expectedErrorMsg: "invalid hello command: invalid capability",
+		},
+		"Hello invalid payload - Empty name": {
+			indicator:        HelloIndicator,
+			data:             []byte("1 0 heartbeat "),
+			expectedErrorMsg: "invalid payload: empty name",
+		},
+		"Hello invalid validation - Name": {
+			indicator:        HelloIndicator,
+			data:             []byte("1 0 heartbeat bad/name"),
+			expectedErrorMsg: "invalid hello command: invalid name",
+		},
+		"Receive_message invalid payload - Headers": {
+			indicator:        ReceiveMessageIndicator,
+			data:             []byte("Bidule?key=%zz Mon super message"),
+			expectedErrorMsg: "invalid payload: invalid headers",
+		},
+		"Receive_message invalid validation - Headers": {
 			indicator:        ReceiveMessageIndicator,
-			data:             []byte("Bidule Invalide message chars &*&*"),
-			expectedErrorMsg: "invalid receive_message command: invalid message",
+			data:             []byte("Bidule?=value Mon super message"),
+			expectedErrorMsg: "invalid receive_message command: invalid headers",
+		},
+		"Receive_message invalid payload - Message ID": {
+			indicator:        ReceiveMessageIndicator,
+			data:             []byte("Bidule: Mon super message"),
+			expectedErrorMsg: "invalid payload: empty message id",
+		},
+		"Publish_message invalid validation - Message ID": {
+			indicator:        PublishMessageIndicator,
+			data:             []byte("Bidule:abc%def Mon super message"),
+			expectedErrorMsg: "invalid publish_message command: invalid message_id",
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -11,9 +11,27 @@ var tunnelNameValidator = regexp.MustCompile(`^[a-zA-Z_.\-\d]+$`)
 type TunnelType byte
 
 const (
+	// BroadcastTunnel transfers every message to all the listeners.
 	BroadcastTunnel TunnelType = iota
+	// QueueTunnel transfers every message to a single listener, the listeners being competing consumers.
+	QueueTunnel
+	// TopicTunnel has a hierarchical name (dot separated segments) and can be listened with wildcard patterns.
+	TopicTunnel
 )
 
+func (t TunnelType) String() string {
+	switch t {
+	case BroadcastTunnel:
+		return "broadcast"
+	case QueueTunnel:
+		return "queue"
+	case TopicTunnel:
+		return "topic"
+	default:
+		return fmt.Sprintf("unknown(%d)", t)
+	}
+}
+
 type CreateTunnel struct {
 	transactionID string
 
@@ -46,12 +64,18 @@ func NewCreateTunnelWithTransactionID(transactionID, name string) *CreateTunnel
 }
 
 func (cmd *CreateTunnel) Validate() error {
-	if cmd.Type != BroadcastTunnel {
+	switch cmd.Type {
+	case BroadcastTunnel, QueueTunnel:
+		if !tunnelNameValidator.MatchString(cmd.Name) {
+			return fmt.Errorf("invalid name")
+		}
+	case TopicTunnel:
+		if !topicNameValidator.MatchString(cmd.Name) {
+			return fmt.Errorf("invalid name")
+		}
+	default:
 		return fmt.Errorf("invalid type")
 	}
-	if !tunnelNameValidator.MatchString(cmd.Name) {
-		return fmt.Errorf("invalid name")
-	}
 	// TODO : Limit name size
 	return nil
 }
@@ -21,3 +21,10 @@ func TestCreateTunnel_Indicator(t *testing.T) {
 func TestCreateTunnel_Data(t *testing.T) {
 	assert.Equal(t, data([]byte{0x00}, []byte("Bidule")), NewCreateTunnel("Bidule").Data())
 }
+
+func TestTunnelType_String(t *testing.T) {
+	assert.Equal(t, "broadcast", BroadcastTunnel.String())
+	assert.Equal(t, "queue", QueueTunnel.String())
+	assert.Equal(t, "topic", TopicTunnel.String())
+	assert.Equal(t, "unknown(42)", TunnelType(42).String())
+}
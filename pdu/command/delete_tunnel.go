@@ -0,0 +1,56 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type DeleteTunnel struct {
+	transactionID string
+
+	Name string
+}
+
+func NewDeleteTunnel(name string) *DeleteTunnel {
+	return &DeleteTunnel{
+		transactionID: newID(),
+		Name:          name,
+	}
+}
+
+func NewDeleteTunnelWithTransactionID(transactionID, name string) *DeleteTunnel {
+	cmd := NewDeleteTunnel(name)
+	cmd.transactionID = transactionID
+	return cmd
+}
+
+func parseDeleteTunnel(transactionID string, data []byte) (Command, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid payload: missing tunnel name")
+	}
+
+	cmd := NewDeleteTunnelWithTransactionID(transactionID, string(data))
+	err := cmd.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid delete_tunnel command: %s", err)
+	}
+	return cmd, nil
+}
+
+func (cmd *DeleteTunnel) Validate() error {
+	if !tunnelNameValidator.MatchString(cmd.Name) {
+		return fmt.Errorf("invalid name")
+	}
+	return nil
+}
+
+func (cmd *DeleteTunnel) Info() string {
+	return fmt.Sprintf("DELETE_TUNNEL(%s)", cmd.Name)
+}
+func (cmd *DeleteTunnel) TransactionID() string { return cmd.transactionID }
+func (cmd *DeleteTunnel) Indicator() byte       { return DeleteTunnelIndicator }
+func (cmd *DeleteTunnel) Data() []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(cmd.Name)
+	return buf.Bytes()
+}
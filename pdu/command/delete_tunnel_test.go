@@ -0,0 +1,23 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteTunnel_Info(t *testing.T) {
+	assert.Equal(t, "DELETE_TUNNEL(Bidule)", NewDeleteTunnel("Bidule").Info())
+}
+
+func TestDeleteTunnel_TransactionID(t *testing.T) {
+	assert.Equal(t, newIDValue, NewDeleteTunnel("Bidule").TransactionID())
+}
+
+func TestDeleteTunnel_Indicator(t *testing.T) {
+	assert.Equal(t, DeleteTunnelIndicator, NewDeleteTunnel("Bidule").Indicator())
+}
+
+func TestDeleteTunnel_Data(t *testing.T) {
+	assert.Equal(t, []byte("Bidule"), NewDeleteTunnel("Bidule").Data())
+}
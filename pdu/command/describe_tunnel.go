@@ -0,0 +1,56 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type DescribeTunnel struct {
+	transactionID string
+
+	Name string
+}
+
+func NewDescribeTunnel(name string) *DescribeTunnel {
+	return &DescribeTunnel{
+		transactionID: newID(),
+		Name:          name,
+	}
+}
+
+func NewDescribeTunnelWithTransactionID(transactionID, name string) *DescribeTunnel {
+	cmd := NewDescribeTunnel(name)
+	cmd.transactionID = transactionID
+	return cmd
+}
+
+func parseDescribeTunnel(transactionID string, data []byte) (Command, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid payload: missing tunnel name")
+	}
+
+	cmd := NewDescribeTunnelWithTransactionID(transactionID, string(data))
+	err := cmd.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid describe_tunnel command: %s", err)
+	}
+	return cmd, nil
+}
+
+func (cmd *DescribeTunnel) Validate() error {
+	if !tunnelNameValidator.MatchString(cmd.Name) {
+		return fmt.Errorf("invalid name")
+	}
+	return nil
+}
+
+func (cmd *DescribeTunnel) Info() string {
+	return fmt.Sprintf("DESCRIBE_TUNNEL(%s)", cmd.Name)
+}
+func (cmd *DescribeTunnel) TransactionID() string { return cmd.transactionID }
+func (cmd *DescribeTunnel) Indicator() byte       { return DescribeTunnelIndicator }
+func (cmd *DescribeTunnel) Data() []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(cmd.Name)
+	return buf.Bytes()
+}
@@ -0,0 +1,23 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeTunnel_Info(t *testing.T) {
+	assert.Equal(t, "DESCRIBE_TUNNEL(Bidule)", NewDescribeTunnel("Bidule").Info())
+}
+
+func TestDescribeTunnel_TransactionID(t *testing.T) {
+	assert.Equal(t, newIDValue, NewDescribeTunnel("Bidule").TransactionID())
+}
+
+func TestDescribeTunnel_Indicator(t *testing.T) {
+	assert.Equal(t, DescribeTunnelIndicator, NewDescribeTunnel("Bidule").Indicator())
+}
+
+func TestDescribeTunnel_Data(t *testing.T) {
+	assert.Equal(t, []byte("Bidule"), NewDescribeTunnel("Bidule").Data())
+}
@@ -0,0 +1,57 @@
+package command
+
+import "fmt"
+
+const (
+	pingData = "PING"
+	pongData = "PONG"
+)
+
+type (
+	// Ping checks that the other end of the connection is alive, it must be answered by a Pong (same transaction id).
+	Ping struct {
+		transactionID string
+	}
+
+	// Pong answers a Ping.
+	Pong struct {
+		transactionID string
+	}
+)
+
+func parseHeartbeat(transactionID string, data []byte) (Command, error) {
+	var cmd Command
+	switch string(data) {
+	case pingData:
+		cmd = NewPingWithTransactionID(transactionID)
+	case pongData:
+		cmd = NewPongWithTransactionID(transactionID)
+	default:
+		return nil, fmt.Errorf("invalid heartbeat command: unknown data %q", string(data))
+	}
+	return cmd, nil
+}
+
+func NewPing() *Ping { return &Ping{transactionID: newID()} }
+func NewPingWithTransactionID(transactionID string) *Ping {
+	cmd := NewPing()
+	cmd.transactionID = transactionID
+	return cmd
+}
+func (ping *Ping) Validate() error       { return nil }
+func (ping *Ping) Info() string          { return "PING" }
+func (ping *Ping) TransactionID() string { return ping.transactionID }
+func (ping *Ping) Indicator() byte       { return HeartbeatIndicator }
+func (ping *Ping) Data() []byte          { return []byte(pingData) }
+
+func NewPong() *Pong { return &Pong{transactionID: newID()} }
+func NewPongWithTransactionID(transactionID string) *Pong {
+	cmd := NewPong()
+	cmd.transactionID = transactionID
+	return cmd
+}
+func (pong *Pong) Validate() error       { return nil }
+func (pong *Pong) Info() string          { return "PONG" }
+func (pong *Pong) TransactionID() string { return pong.transactionID }
+func (pong *Pong) Indicator() byte       { return HeartbeatIndicator }
+func (pong *Pong) Data() []byte          { return []byte(pongData) }
@@ -0,0 +1,39 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPing_Info(t *testing.T) {
+	assert.Equal(t, "PING", NewPing().Info())
+}
+
+func TestPing_TransactionID(t *testing.T) {
+	assert.Equal(t, newIDValue, NewPing().TransactionID())
+}
+
+func TestPing_Indicator(t *testing.T) {
+	assert.Equal(t, HeartbeatIndicator, NewPing().Indicator())
+}
+
+func TestPing_Data(t *testing.T) {
+	assert.Equal(t, []byte(pingData), NewPing().Data())
+}
+
+func TestPong_Info(t *testing.T) {
+	assert.Equal(t, "PONG", NewPong().Info())
+}
+
+func TestPong_TransactionID(t *testing.T) {
+	assert.Equal(t, newIDValue, NewPong().TransactionID())
+}
+
+func TestPong_Indicator(t *testing.T) {
+	assert.Equal(t, HeartbeatIndicator, NewPong().Indicator())
+}
+
+func TestPong_Data(t *testing.T) {
+	assert.Equal(t, []byte(pongData), NewPong().Data())
+}
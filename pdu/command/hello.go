@@ -0,0 +1,181 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the version of the protocol implemented by this package.
+const ProtocolVersion byte = 1
+
+var (
+	capabilityValidator = regexp.MustCompile(`^[a-z_\d]+$`)
+	helloNameValidator  = regexp.MustCompile(`^[a-zA-Z_.\-\d]{1,64}$`)
+)
+
+// Capability is an optional protocol feature negotiated during the handshake.
+type Capability string
+
+const (
+	// LengthPrefixedFramingCapability switches the connection to the length-prefixed framing once the handshake is done.
+	LengthPrefixedFramingCapability Capability = "length_prefixed_framing"
+	// HeartbeatCapability indicates that both ends answer the Ping commands.
+	HeartbeatCapability Capability = "heartbeat"
+	// QueueTunnelCapability indicates that the server supports the QueueTunnel type.
+	QueueTunnelCapability Capability = "queue_tunnel"
+	// TopicTunnelCapability indicates that the server supports the TopicTunnel type and the wildcard listening.
+	TopicTunnelCapability Capability = "topic_tunnel"
+	// DeflateCompressionCapability allows to compress the commands' data with deflate (requires the length-prefixed framing).
+	DeflateCompressionCapability Capability = "deflate_compression"
+	// GzipCompressionCapability allows to compress the commands' data with gzip (requires the length-prefixed framing).
+	GzipCompressionCapability Capability = "gzip_compression"
+	// HeadersCapability indicates that both ends carry the headers of the messages.
+	HeadersCapability Capability = "headers"
+	// MessageIDCapability indicates that both ends carry the id of the messages.
+	MessageIDCapability Capability = "message_id"
+	// OpenPublishCapability indicates that the server accepts the messages published to a Tunnel the publisher isn't listening to.
+	OpenPublishCapability Capability = "open_publish"
+)
+
+// Capabilities returns every capability of the protocol.
+func Capabilities() []Capability {
+	return []Capability{
+		LengthPrefixedFramingCapability,
+		HeartbeatCapability,
+		QueueTunnelCapability,
+		TopicTunnelCapability,
+		DeflateCompressionCapability,
+		GzipCompressionCapability,
+		HeadersCapability,
+		MessageIDCapability,
+		OpenPublishCapability,
+	}
+}
+
+// Hello is the handshake command.
+// The client sends its offer, the server replies with a Hello (same transaction id) holding the agreed values.
+type Hello struct {
+	transactionID string
+
+	Version byte
+	// MaxFrameSize is the maximum payload size in bytes. 0 means unlimited.
+	MaxFrameSize uint32
+	Capabilities []Capability
+	// Name of the sender (optional), i.e. to identify the client in the server logs.
+	Name string
+}
+
+func parseHello(transactionID string, data []byte) (Command, error) {
+	fields := strings.Split(string(data), " ")
+	if len(fields) < 2 || len(fields) > 4 {
+		return nil, fmt.Errorf("invalid payload: expected 2 to 4 values, got %d", len(fields))
+	}
+
+	version, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: invalid version %q", fields[0])
+	}
+	maxFrameSize, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: invalid max frame size %q", fields[1])
+	}
+
+	cmd := NewHelloWithTransactionID(transactionID)
+	cmd.Version = byte(version)
+	cmd.MaxFrameSize = uint32(maxFrameSize)
+	// The capabilities are empty when only the name is given.
+	if len(fields) >= 3 && (fields[2] != "" || len(fields) == 3) {
+		for _, capability := range strings.Split(fields[2], ",") {
+			cmd.Capabilities = append(cmd.Capabilities, Capability(capability))
+		}
+	}
+	if len(fields) == 4 {
+		cmd.Name = fields[3]
+		if cmd.Name == "" {
+			return nil, fmt.Errorf("invalid payload: empty name")
+		}
+	}
+
+	err = cmd.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid hello command: %s", err)
+	}
+	return cmd, nil
+}
+
+func NewHello() *Hello {
+	return &Hello{transactionID: newID(), Version: ProtocolVersion}
+}
+
+func NewHelloWithTransactionID(transactionID string) *Hello {
+	cmd := NewHello()
+	cmd.transactionID = transactionID
+	return cmd
+}
+
+// Has determines if the given capability is part of the Hello.
+func (cmd *Hello) Has(capability Capability) bool {
+	return slices.Contains(cmd.Capabilities, capability)
+}
+
+// Negotiate returns the Hello replying to the given offer.
+// It holds the lowest version, the lowest frame size and the capabilities supported by both.
+func (cmd *Hello) Negotiate(offer *Hello) *Hello {
+	agreed := NewHelloWithTransactionID(offer.TransactionID())
+	agreed.Version = min(cmd.Version, offer.Version)
+	agreed.MaxFrameSize = max(cmd.MaxFrameSize, offer.MaxFrameSize)
+	if cmd.MaxFrameSize != 0 && offer.MaxFrameSize != 0 {
+		agreed.MaxFrameSize = min(cmd.MaxFrameSize, offer.MaxFrameSize)
+	}
+	for _, capability := range offer.Capabilities {
+		if cmd.Has(capability) {
+			agreed.Capabilities = append(agreed.Capabilities, capability)
+		}
+	}
+	return agreed
+}
+
+func (cmd *Hello) Validate() error {
+	if cmd.Version == 0 {
+		return fmt.Errorf("invalid version")
+	}
+	for _, capability := range cmd.Capabilities {
+		if !capabilityValidator.MatchString(string(capability)) {
+			return fmt.Errorf("invalid capability")
+		}
+	}
+	if cmd.Name != "" && !helloNameValidator.MatchString(cmd.Name) {
+		return fmt.Errorf("invalid name")
+	}
+	return nil
+}
+
+func (cmd *Hello) Info() string {
+	return fmt.Sprintf("HELLO(v%d)", cmd.Version)
+}
+func (cmd *Hello) TransactionID() string { return cmd.transactionID }
+func (cmd *Hello) Indicator() byte       { return HelloIndicator }
+func (cmd *Hello) Data() []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(strconv.Itoa(int(cmd.Version)))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatUint(uint64(cmd.MaxFrameSize), 10))
+	if len(cmd.Capabilities) > 0 || cmd.Name != "" {
+		buf.WriteByte(' ')
+		for i, capability := range cmd.Capabilities {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(string(capability))
+		}
+	}
+	if cmd.Name != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(cmd.Name)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHello_Info(t *testing.T) {
+	assert.Equal(t, "HELLO(v1)", NewHello().Info())
+}
+
+func TestHello_TransactionID(t *testing.T) {
+	assert.Equal(t, newIDValue, NewHello().TransactionID())
+}
+
+func TestHello_Indicator(t *testing.T) {
+	assert.Equal(t, HelloIndicator, NewHello().Indicator())
+}
+
+func TestHello_Data(t *testing.T) {
+	cmd := NewHello()
+	assert.Equal(t, []byte("1 0"), cmd.Data())
+
+	cmd.MaxFrameSize = 1024
+	cmd.Capabilities = []Capability{LengthPrefixedFramingCapability, "other"}
+	assert.Equal(t, []byte("1 1024 length_prefixed_framing,other"), cmd.Data())
+
+	cmd.Name = "billing-service"
+	assert.Equal(t, []byte("1 1024 length_prefixed_framing,other billing-service"), cmd.Data())
+
+	cmd.Capabilities = nil
+	assert.Equal(t, []byte("1 1024  billing-service"), cmd.Data())
+}
+
+func TestHello_Negotiate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		server   *Hello
+		offer    *Hello
+		expected *Hello
+	}{
+		"Same values": {
+			server:   &Hello{Version: 1, MaxFrameSize: 1024, Capabilities: []Capability{"a", "b"}},
+			offer:    &Hello{transactionID: "efgh5678", Version: 1, MaxFrameSize: 1024, Capabilities: []Capability{"a", "b"}},
+			expected: &Hello{transactionID: "efgh5678", Version: 1, MaxFrameSize: 1024, Capabilities: []Capability{"a", "b"}},
+		},
+		"Lowest values": {
+			server:   &Hello{Version: 1, MaxFrameSize: 1024, Capabilities: []Capability{"a", "b"}},
+			offer:    &Hello{transactionID: "efgh5678", Version: 2, MaxFrameSize: 2048, Capabilities: []Capability{"b", "c"}},
+			expected: &Hello{transactionID: "efgh5678", Version: 1, MaxFrameSize: 1024, Capabilities: []Capability{"b"}},
+		},
+		"Unlimited frame size": {
+			server:   &Hello{Version: 1, MaxFrameSize: 0},
+			offer:    &Hello{transactionID: "efgh5678", Version: 1, MaxFrameSize: 2048},
+			expected: &Hello{transactionID: "efgh5678", Version: 1, MaxFrameSize: 2048},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.server.Negotiate(tc.offer))
+		})
+	}
+}
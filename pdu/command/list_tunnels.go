@@ -0,0 +1,69 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+var tunnelPatternValidator = regexp.MustCompile(`^[a-zA-Z_.\-\d*]+$`)
+
+// ListTunnels asks the server for the existing Tunnels.
+// The server replies with a TunnelInfo (same transaction id).
+type ListTunnels struct {
+	transactionID string
+
+	// Pattern filters the Tunnels by name, '*' matching any sequence of characters.
+	// Empty means all the Tunnels.
+	Pattern string
+}
+
+func NewListTunnels(pattern string) *ListTunnels {
+	return &ListTunnels{
+		transactionID: newID(),
+		Pattern:       pattern,
+	}
+}
+
+func NewListTunnelsWithTransactionID(transactionID, pattern string) *ListTunnels {
+	cmd := NewListTunnels(pattern)
+	cmd.transactionID = transactionID
+	return cmd
+}
+
+func parseListTunnels(transactionID string, data []byte) (Command, error) {
+	cmd := NewListTunnelsWithTransactionID(transactionID, string(data))
+	err := cmd.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid list_tunnels command: %s", err)
+	}
+	return cmd, nil
+}
+
+// Match determines if the Tunnel's name matches the Pattern.
+func (cmd *ListTunnels) Match(name string) bool {
+	if cmd.Pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(cmd.Pattern, name)
+	return matched
+}
+
+func (cmd *ListTunnels) Validate() error {
+	if cmd.Pattern != "" && !tunnelPatternValidator.MatchString(cmd.Pattern) {
+		return fmt.Errorf("invalid pattern")
+	}
+	return nil
+}
+
+func (cmd *ListTunnels) Info() string {
+	return fmt.Sprintf("LIST_TUNNELS(%s)", cmd.Pattern)
+}
+func (cmd *ListTunnels) TransactionID() string { return cmd.transactionID }
+func (cmd *ListTunnels) Indicator() byte       { return ListTunnelsIndicator }
+func (cmd *ListTunnels) Data() []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(cmd.Pattern)
+	return buf.Bytes()
+}
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListTunnels_Info(t *testing.T) {
+	assert.Equal(t, "LIST_TUNNELS(Bid*)", NewListTunnels("Bid*").Info())
+}
+
+func TestListTunnels_TransactionID(t *testing.T) {
+	assert.Equal(t, newIDValue, NewListTunnels("").TransactionID())
+}
+
+func TestListTunnels_Indicator(t *testing.T) {
+	assert.Equal(t, ListTunnelsIndicator, NewListTunnels("").Indicator())
+}
+
+func TestListTunnels_Data(t *testing.T) {
+	assert.Equal(t, []byte("Bid*"), NewListTunnels("Bid*").Data())
+}
+
+func TestListTunnels_Match(t *testing.T) {
+	for name, tc := range map[string]struct {
+		pattern  string
+		expected bool
+	}{
+		"No pattern":         {pattern: "", expected: true},
+		"Exact name":         {pattern: "Bidule", expected: true},
+		"Other name":         {pattern: "Machin", expected: false},
+		"Prefix wildcard":    {pattern: "Bid*", expected: true},
+		"Suffix wildcard":    {pattern: "*ule", expected: true},
+		"Wildcard only":      {pattern: "*", expected: true},
+		"Unmatched wildcard": {pattern: "Ma*", expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, NewListTunnels(tc.pattern).Match("Bidule"))
+		})
+	}
+}
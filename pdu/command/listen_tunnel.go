@@ -38,7 +38,7 @@ func parseListenTunnel(transactionID string, data []byte) (Command, error) {
 }
 
 func (cmd *ListenTunnel) Validate() error {
-	if !tunnelNameValidator.MatchString(cmd.Name) {
+	if !validListenName(cmd.Name) {
 		return fmt.Errorf("invalid name")
 	}
 	return nil
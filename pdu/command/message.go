@@ -0,0 +1,85 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var messageIDValidator = regexp.MustCompile(`^[a-zA-Z_\-\d]{1,64}$`)
+
+// Headers are key / value metadata carried along a message (content-type, correlation id, ...).
+type Headers map[string]string
+
+// messageData is the data shared by the commands carrying a message.
+// It is encoded as `<tunnel_name>[:<message_id>][?<headers>] <message>` where headers are URL query encoded.
+type messageData struct {
+	TunnelName string
+	MessageID  string
+	Headers    Headers
+	Message    []byte
+}
+
+func parseMessageData(data []byte) (messageData, error) {
+	head, message, found := bytes.Cut(data, []byte(" "))
+	if !found {
+		return messageData{}, fmt.Errorf("invalid payload: missing separator, cannot determine values")
+	}
+
+	head, rawHeaders, hasHeaders := bytes.Cut(head, []byte("?"))
+	tunnelName, messageID, hasMessageID := bytes.Cut(head, []byte(":"))
+	parsed := messageData{TunnelName: string(tunnelName), Message: message}
+	if hasMessageID {
+		if len(messageID) == 0 {
+			return messageData{}, fmt.Errorf("invalid payload: empty message id")
+		}
+		parsed.MessageID = string(messageID)
+	}
+	if hasHeaders {
+		values, err := url.ParseQuery(string(rawHeaders))
+		if err != nil {
+			return messageData{}, fmt.Errorf("invalid payload: invalid headers")
+		}
+		parsed.Headers = make(Headers, len(values))
+		for key := range values {
+			parsed.Headers[key] = values.Get(key)
+		}
+	}
+	return parsed, nil
+}
+
+func (m messageData) validate() error {
+	if !tunnelNameValidator.MatchString(m.TunnelName) {
+		return fmt.Errorf("invalid tunnel_name")
+	}
+	if m.MessageID != "" && !messageIDValidator.MatchString(m.MessageID) {
+		return fmt.Errorf("invalid message_id")
+	}
+	for key := range m.Headers {
+		if key == "" {
+			return fmt.Errorf("invalid headers")
+		}
+	}
+	return nil
+}
+
+func (m messageData) data() []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(m.TunnelName)
+	if m.MessageID != "" {
+		buf.WriteByte(':')
+		buf.WriteString(m.MessageID)
+	}
+	if len(m.Headers) > 0 {
+		values := make(url.Values, len(m.Headers))
+		for key, value := range m.Headers {
+			values.Set(key, value)
+		}
+		buf.WriteByte('?')
+		buf.WriteString(values.Encode())
+	}
+	buf.WriteByte(' ')
+	buf.Write(m.Message)
+	return buf.Bytes()
+}
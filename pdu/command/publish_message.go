@@ -1,38 +1,35 @@
 package command
 
-import (
-	"bytes"
-	"fmt"
-	"regexp"
-)
-
-var messageValidator = regexp.MustCompile(`^[a-zA-Z0-9 _\d]+$`)
+import "fmt"
 
 type PublishMessage struct {
 	transactionID string
 
 	TunnelName string
-	Message    string
+	// MessageID identifies the message from the publisher to the listeners.
+	// Set by the publisher, the server generates one when empty.
+	MessageID string
+	Headers   Headers
+	Message   []byte
 }
 
 func parsePublishMessage(transactionID string, data []byte) (Command, error) {
-	separatorIdx := bytes.Index(data, []byte(" "))
-	if separatorIdx == -1 {
-		return nil, fmt.Errorf("invalid payload: missing separator, cannot determine values")
+	parsed, err := parseMessageData(data)
+	if err != nil {
+		return nil, err
 	}
 
-	tunnelName := data[:separatorIdx]
-	message := data[separatorIdx+1:]
-
-	cmd := NewPublishMessageWithTransactionID(transactionID, string(tunnelName), string(message))
-	err := cmd.Validate()
+	cmd := NewPublishMessageWithTransactionID(transactionID, parsed.TunnelName, parsed.Message)
+	cmd.MessageID = parsed.MessageID
+	cmd.Headers = parsed.Headers
+	err = cmd.Validate()
 	if err != nil {
 		return nil, fmt.Errorf("invalid publish_message command: %s", err)
 	}
 	return cmd, nil
 }
 
-func NewPublishMessage(tunnelName, message string) *PublishMessage {
+func NewPublishMessage(tunnelName string, message []byte) *PublishMessage {
 	return &PublishMessage{
 		transactionID: newID(),
 		TunnelName:    tunnelName,
@@ -40,20 +37,14 @@ func NewPublishMessage(tunnelName, message string) *PublishMessage {
 	}
 }
 
-func NewPublishMessageWithTransactionID(transactionID, tunnelName, message string) *PublishMessage {
+func NewPublishMessageWithTransactionID(transactionID, tunnelName string, message []byte) *PublishMessage {
 	cmd := NewPublishMessage(tunnelName, message)
 	cmd.transactionID = transactionID
 	return cmd
 }
 
 func (cmd *PublishMessage) Validate() error {
-	if !tunnelNameValidator.MatchString(cmd.TunnelName) {
-		return fmt.Errorf("invalid tunnel_name")
-	}
-	if !messageValidator.MatchString(cmd.Message) {
-		return fmt.Errorf("invalid message")
-	}
-	return nil
+	return cmd.messageData().validate()
 }
 
 func (cmd *PublishMessage) Info() string {
@@ -69,9 +60,9 @@ func (cmd *PublishMessage) Indicator() byte {
 }
 
 func (cmd *PublishMessage) Data() []byte {
-	buf := bytes.Buffer{}
-	buf.WriteString(cmd.TunnelName)
-	buf.WriteByte(' ')
-	buf.WriteString(cmd.Message)
-	return buf.Bytes()
+	return cmd.messageData().data()
+}
+
+func (cmd *PublishMessage) messageData() messageData {
+	return messageData{TunnelName: cmd.TunnelName, MessageID: cmd.MessageID, Headers: cmd.Headers, Message: cmd.Message}
 }
@@ -7,17 +7,30 @@ import (
 )
 
 func TestPublishMessage_Info(t *testing.T) {
-	assert.Equal(t, "PUBLISH_MESSAGE[Bidule]message_size(4)", NewPublishMessage("Bidule", "toto").Info())
+	assert.Equal(t, "PUBLISH_MESSAGE[Bidule]message_size(4)", NewPublishMessage("Bidule", []byte("toto")).Info())
 }
 
 func TestPublishMessage_TransactionID(t *testing.T) {
-	assert.Equal(t, newIDValue, NewPublishMessage("Bidule", "toto").TransactionID())
+	assert.Equal(t, newIDValue, NewPublishMessage("Bidule", []byte("toto")).TransactionID())
 }
 
 func TestPublishMessage_Indicator(t *testing.T) {
-	assert.Equal(t, PublishMessageIndicator, NewPublishMessage("Bidule", "toto").Indicator())
+	assert.Equal(t, PublishMessageIndicator, NewPublishMessage("Bidule", []byte("toto")).Indicator())
 }
 
 func TestPublishMessage_Data(t *testing.T) {
-	assert.Equal(t, data([]byte("Bidule"), []byte{' '}, []byte("toto")), NewPublishMessage("Bidule", "toto").Data())
+	assert.Equal(t, data([]byte("Bidule"), []byte{' '}, []byte("toto")), NewPublishMessage("Bidule", []byte("toto")).Data())
+}
+
+func TestPublishMessage_Data_WithHeaders(t *testing.T) {
+	cmd := NewPublishMessage("Bidule", []byte("toto"))
+	cmd.Headers = Headers{"content-type": "application/json", "trace id": "a&b=c"}
+	assert.Equal(t, []byte("Bidule?content-type=application%2Fjson&trace+id=a%26b%3Dc toto"), cmd.Data())
+}
+
+func TestPublishMessage_Data_WithMessageID(t *testing.T) {
+	cmd := NewPublishMessage("Bidule", []byte("toto"))
+	cmd.MessageID = "9m4e2mr0ui3e8a215n4g"
+	cmd.Headers = Headers{"content-type": "text/plain"}
+	assert.Equal(t, []byte("Bidule:9m4e2mr0ui3e8a215n4g?content-type=text%2Fplain toto"), cmd.Data())
 }
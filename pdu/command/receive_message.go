@@ -1,35 +1,35 @@
 package command
 
-import (
-	"bytes"
-	"fmt"
-)
+import "fmt"
 
 type ReceiveMessage struct {
 	transactionID string
 
 	TunnelName string
-	Message    string
+	// MessageID identifies the message from the publisher to the listeners.
+	// Set by the publisher or the server, it is the same for every delivery of the message.
+	MessageID string
+	Headers   Headers
+	Message   []byte
 }
 
 func parseReceiveMessage(transactionID string, data []byte) (Command, error) {
-	separatorIdx := bytes.Index(data, []byte(" "))
-	if separatorIdx == -1 {
-		return nil, fmt.Errorf("invalid payload: missing separator, cannot determine values")
+	parsed, err := parseMessageData(data)
+	if err != nil {
+		return nil, err
 	}
 
-	tunnelName := data[:separatorIdx]
-	message := data[separatorIdx+1:]
-
-	cmd := NewReceiveMessageWithTransactionID(transactionID, string(tunnelName), string(message))
-	err := cmd.Validate()
+	cmd := NewReceiveMessageWithTransactionID(transactionID, parsed.TunnelName, parsed.Message)
+	cmd.MessageID = parsed.MessageID
+	cmd.Headers = parsed.Headers
+	err = cmd.Validate()
 	if err != nil {
 		return nil, fmt.Errorf("invalid receive_message command: %s", err)
 	}
 	return cmd, nil
 }
 
-func NewReceiveMessage(tunnelName, message string) *ReceiveMessage {
+func NewReceiveMessage(tunnelName string, message []byte) *ReceiveMessage {
 	return &ReceiveMessage{
 		transactionID: newID(),
 		TunnelName:    tunnelName,
@@ -37,20 +37,14 @@ func NewReceiveMessage(tunnelName, message string) *ReceiveMessage {
 	}
 }
 
-func NewReceiveMessageWithTransactionID(transactionID, tunnelName, message string) *ReceiveMessage {
+func NewReceiveMessageWithTransactionID(transactionID, tunnelName string, message []byte) *ReceiveMessage {
 	cmd := NewReceiveMessage(tunnelName, message)
 	cmd.transactionID = transactionID
 	return cmd
 }
 
 func (cmd *ReceiveMessage) Validate() error {
-	if !tunnelNameValidator.MatchString(cmd.TunnelName) {
-		return fmt.Errorf("invalid tunnel_name")
-	}
-	if !messageValidator.MatchString(cmd.Message) {
-		return fmt.Errorf("invalid message")
-	}
-	return nil
+	return cmd.messageData().validate()
 }
 
 func (cmd *ReceiveMessage) Info() string {
@@ -66,9 +60,9 @@ func (cmd *ReceiveMessage) Indicator() byte {
 }
 
 func (cmd *ReceiveMessage) Data() []byte {
-	buf := bytes.Buffer{}
-	buf.WriteString(cmd.TunnelName)
-	buf.WriteByte(' ')
-	buf.WriteString(cmd.Message)
-	return buf.Bytes()
+	return cmd.messageData().data()
+}
+
+func (cmd *ReceiveMessage) messageData() messageData {
+	return messageData{TunnelName: cmd.TunnelName, MessageID: cmd.MessageID, Headers: cmd.Headers, Message: cmd.Message}
 }
@@ -7,17 +7,23 @@ import (
 )
 
 func TestReceiveMessage_Info(t *testing.T) {
-	assert.Equal(t, "RECEIVE_MESSAGE[Bidule]message_size(4)", NewReceiveMessage("Bidule", "toto").Info())
+	assert.Equal(t, "RECEIVE_MESSAGE[Bidule]message_size(4)", NewReceiveMessage("Bidule", []byte("toto")).Info())
 }
 
 func TestReceiveMessage_TransactionID(t *testing.T) {
-	assert.Equal(t, newIDValue, NewReceiveMessage("Bidule", "toto").TransactionID())
+	assert.Equal(t, newIDValue, NewReceiveMessage("Bidule", []byte("toto")).TransactionID())
 }
 
 func TestReceiveMessage_Indicator(t *testing.T) {
-	assert.Equal(t, ReceiveMessageIndicator, NewReceiveMessage("Bidule", "toto").Indicator())
+	assert.Equal(t, ReceiveMessageIndicator, NewReceiveMessage("Bidule", []byte("toto")).Indicator())
 }
 
 func TestReceiveMessage_Data(t *testing.T) {
-	assert.Equal(t, data([]byte("Bidule"), []byte{' '}, []byte("toto")), NewReceiveMessage("Bidule", "toto").Data())
+	assert.Equal(t, data([]byte("Bidule"), []byte{' '}, []byte("toto")), NewReceiveMessage("Bidule", []byte("toto")).Data())
+}
+
+func TestReceiveMessage_Data_WithHeaders(t *testing.T) {
+	cmd := NewReceiveMessage("Bidule", []byte("toto"))
+	cmd.Headers = Headers{"content-type": "application/json", "trace id": "a&b=c"}
+	assert.Equal(t, []byte("Bidule?content-type=application%2Fjson&trace+id=a%26b%3Dc toto"), cmd.Data())
 }
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/codingLayce/tunnel.go/common/maps"
+)
+
+// ErrUnknownIndicator is returned by Parse when no Parser is registered for the indicator.
+var ErrUnknownIndicator = errors.New("invalid command indicator")
+
+// Parser parses the transaction id and the data of a payload to the corresponding command.
+// It must return an error if the data is invalid.
+type Parser func(transactionID string, data []byte) (Command, error)
+
+// Registry associates the commands indicator to their Parser.
+type Registry struct {
+	parsers *maps.SyncMap[byte, Parser]
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{parsers: maps.NewSyncMap[byte, Parser]()}
+}
+
+// DefaultRegistry holds the commands of the protocol and the ones registered with Register.
+// It's used by Parse.
+var DefaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.mustRegister(AcknowledgementIndicator, parseAcknowledgement)
+	registry.mustRegister(CreateTunnelIndicator, parseCreateTunnel)
+	registry.mustRegister(ListenTunnelIndicator, parseListenTunnel)
+	registry.mustRegister(PublishMessageIndicator, parsePublishMessage)
+	registry.mustRegister(ReceiveMessageIndicator, parseReceiveMessage)
+	registry.mustRegister(HelloIndicator, parseHello)
+	registry.mustRegister(UnlistenTunnelIndicator, parseUnlistenTunnel)
+	registry.mustRegister(DeleteTunnelIndicator, parseDeleteTunnel)
+	registry.mustRegister(ListTunnelsIndicator, parseListTunnels)
+	registry.mustRegister(DescribeTunnelIndicator, parseDescribeTunnel)
+	registry.mustRegister(TunnelInfoIndicator, parseTunnelInfo)
+	registry.mustRegister(HeartbeatIndicator, parseHeartbeat)
+	return registry
+}
+
+// indicatorFlags are the bits of the indicator reserved for the payload flags (see pdu.CompressedFlag).
+const indicatorFlags byte = 0x80
+
+// Register the parser of the commands with the given indicator.
+// Returns an error if the indicator is already registered or has the high bit set (reserved for the payload flags).
+func (r *Registry) Register(indicator byte, parser Parser) error {
+	if parser == nil {
+		return fmt.Errorf("nil parser for indicator 0x%x", indicator)
+	}
+	if indicator&indicatorFlags != 0 {
+		return fmt.Errorf("invalid indicator 0x%x: the high bit is reserved", indicator)
+	}
+	if !r.parsers.PutIfAbsent(indicator, parser) {
+		return fmt.Errorf("indicator 0x%x already registered", indicator)
+	}
+	return nil
+}
+
+func (r *Registry) mustRegister(indicator byte, parser Parser) {
+	err := r.Register(indicator, parser)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Parse to the corresponding command.
+// Returns an error if no command fit the given attributes or if it's invalid.
+func (r *Registry) Parse(indicator byte, transactionID string, data []byte) (Command, error) {
+	parser, ok := r.parsers.Get(indicator)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown 0x%x", ErrUnknownIndicator, indicator)
+	}
+	return parser(transactionID, data)
+}
+
+// Register the parser of the commands with the given indicator in the DefaultRegistry.
+// Returns an error if the indicator is already registered or has the high bit set (reserved for the payload flags).
+func Register(indicator byte, parser Parser) error {
+	return DefaultRegistry.Register(indicator, parser)
+}
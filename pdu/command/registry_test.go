@@ -0,0 +1,86 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/test-helper/mock"
+)
+
+type customCommand struct {
+	transactionID string
+	data          []byte
+}
+
+func (cmd *customCommand) Validate() error       { return nil }
+func (cmd *customCommand) Info() string          { return "CUSTOM" }
+func (cmd *customCommand) TransactionID() string { return cmd.transactionID }
+func (cmd *customCommand) Indicator() byte       { return 'c' }
+func (cmd *customCommand) Data() []byte          { return cmd.data }
+
+func parseCustomCommand(transactionID string, data []byte) (Command, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid payload: missing data")
+	}
+	return &customCommand{transactionID: transactionID, data: data}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	_, err := registry.Parse('c', "abcd1234", []byte("data"))
+	assert.EqualError(t, err, "invalid command indicator: unknown 0x63")
+	assert.ErrorIs(t, err, ErrUnknownIndicator)
+
+	err = registry.Register('c', parseCustomCommand)
+	require.NoError(t, err)
+
+	cmd, err := registry.Parse('c', "abcd1234", []byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, &customCommand{transactionID: "abcd1234", data: []byte("data")}, cmd)
+
+	_, err = registry.Parse('c', "abcd1234", nil)
+	assert.EqualError(t, err, "invalid payload: missing data")
+}
+
+func TestRegistry_Register_Errors(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.Register('c', parseCustomCommand))
+
+	assert.EqualError(t, registry.Register('c', parseCustomCommand), "indicator 0x63 already registered")
+	assert.EqualError(t, registry.Register('d', nil), "nil parser for indicator 0x64")
+	assert.EqualError(t, registry.Register(0x80|'c', parseCustomCommand), "invalid indicator 0xe3: the high bit is reserved")
+	assert.EqualError(t, registry.Register(0xff, parseCustomCommand), "invalid indicator 0xff: the high bit is reserved")
+}
+
+func TestRegister(t *testing.T) {
+	mock.Do(t, &DefaultRegistry, NewRegistry())
+
+	require.NoError(t, Register('c', parseCustomCommand))
+
+	cmd, err := Parse('c', "abcd1234", []byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, &customCommand{transactionID: "abcd1234", data: []byte("data")}, cmd)
+}
+
+func TestDefaultRegistry_ProtocolIndicators(t *testing.T) {
+	for _, indicator := range []byte{
+		AcknowledgementIndicator,
+		CreateTunnelIndicator,
+		ListenTunnelIndicator,
+		PublishMessageIndicator,
+		ReceiveMessageIndicator,
+		HelloIndicator,
+		UnlistenTunnelIndicator,
+		DeleteTunnelIndicator,
+		ListTunnelsIndicator,
+		DescribeTunnelIndicator,
+		TunnelInfoIndicator,
+		HeartbeatIndicator,
+	} {
+		assert.Error(t, newDefaultRegistry().Register(indicator, parseCustomCommand), "0x%x should be registered", indicator)
+	}
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// TopicSeparator separates the segments of a TopicTunnel's name.
+	TopicSeparator = "."
+	// SingleSegmentWildcard matches exactly one segment of a TopicTunnel's name.
+	SingleSegmentWildcard = "*"
+	// TrailingSegmentsWildcard matches one or more segments at the end of a TopicTunnel's name.
+	TrailingSegmentsWildcard = ">"
+)
+
+var (
+	topicNameValidator    = regexp.MustCompile(`^[a-zA-Z_\-\d]+(\.[a-zA-Z_\-\d]+)*$`)
+	topicPatternValidator = regexp.MustCompile(`^(>|(\*|[a-zA-Z_\-\d]+)(\.(\*|[a-zA-Z_\-\d]+))*(\.>)?)$`)
+)
+
+// IsTopicPattern determines if the name is a valid TopicTunnel's pattern (i.e. containing a wildcard).
+func IsTopicPattern(name string) bool {
+	return topicPatternValidator.MatchString(name) && !topicNameValidator.MatchString(name)
+}
+
+// MatchTopic determines if the TopicTunnel's name matches the pattern.
+// A pattern without wildcard only matches the same name.
+func MatchTopic(pattern, name string) bool {
+	patternSegments := strings.Split(pattern, TopicSeparator)
+	nameSegments := strings.Split(name, TopicSeparator)
+
+	for i, patternSegment := range patternSegments {
+		if patternSegment == TrailingSegmentsWildcard {
+			return len(nameSegments) > i
+		}
+		if i >= len(nameSegments) {
+			return false
+		}
+		if patternSegment != SingleSegmentWildcard && patternSegment != nameSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(nameSegments)
+}
+
+// validListenName determines if the name can be listened: a Tunnel's name or a TopicTunnel's pattern.
+func validListenName(name string) bool {
+	return tunnelNameValidator.MatchString(name) || topicPatternValidator.MatchString(name)
+}
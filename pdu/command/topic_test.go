@@ -0,0 +1,55 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTopicPattern(t *testing.T) {
+	for name, tc := range map[string]struct {
+		name     string
+		expected bool
+	}{
+		"Name":                      {name: "orders.eu.created", expected: false},
+		"Single segment wildcard":   {name: "orders.*.created", expected: true},
+		"Trailing wildcard":         {name: "orders.>", expected: true},
+		"Trailing wildcard only":    {name: ">", expected: true},
+		"Both wildcards":            {name: "*.eu.>", expected: true},
+		"Trailing wildcard inside":  {name: "orders.>.created", expected: false},
+		"Partial segment wildcard":  {name: "orders.e*.created", expected: false},
+		"Empty segment":             {name: "orders..created", expected: false},
+		"Invalid segment character": {name: "orders.*.cr&ated", expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsTopicPattern(tc.name))
+		})
+	}
+}
+
+func TestMatchTopic(t *testing.T) {
+	for name, tc := range map[string]struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		"Same name":                          {pattern: "orders.eu.created", name: "orders.eu.created", expected: true},
+		"Other name":                         {pattern: "orders.eu.created", name: "orders.us.created", expected: false},
+		"Single segment wildcard":            {pattern: "orders.*.created", name: "orders.eu.created", expected: true},
+		"Single segment wildcard mismatch":   {pattern: "orders.*.created", name: "orders.eu.deleted", expected: false},
+		"Single segment wildcard too short":  {pattern: "orders.*.created", name: "orders.created", expected: false},
+		"Single segment wildcard too long":   {pattern: "orders.*", name: "orders.eu.created", expected: false},
+		"Trailing wildcard":                  {pattern: "orders.>", name: "orders.eu.created", expected: true},
+		"Trailing wildcard single segment":   {pattern: "orders.>", name: "orders.eu", expected: true},
+		"Trailing wildcard without segment":  {pattern: "orders.>", name: "orders", expected: false},
+		"Trailing wildcard mismatch":         {pattern: "orders.>", name: "payments.eu", expected: false},
+		"Trailing wildcard only":             {pattern: ">", name: "orders.eu.created", expected: true},
+		"Both wildcards":                     {pattern: "*.eu.>", name: "orders.eu.created", expected: true},
+		"Both wildcards mismatch":            {pattern: "*.eu.>", name: "orders.us.created", expected: false},
+		"Pattern longer than the topic name": {pattern: "orders.eu.created", name: "orders.eu", expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, MatchTopic(tc.pattern, tc.name))
+		})
+	}
+}
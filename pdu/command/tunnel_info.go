@@ -0,0 +1,104 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TunnelDescription describes a Tunnel known by the server.
+type TunnelDescription struct {
+	Name      string
+	Type      TunnelType
+	Listeners int
+}
+
+func (d TunnelDescription) validate() error {
+	if !tunnelNameValidator.MatchString(d.Name) {
+		return fmt.Errorf("invalid name")
+	}
+	if d.Listeners < 0 {
+		return fmt.Errorf("invalid listeners count")
+	}
+	return nil
+}
+
+// TunnelInfo is the server response to ListTunnels and DescribeTunnel (same transaction id).
+type TunnelInfo struct {
+	transactionID string
+
+	Tunnels []TunnelDescription
+}
+
+func NewTunnelInfo(tunnels ...TunnelDescription) *TunnelInfo {
+	return &TunnelInfo{
+		transactionID: newID(),
+		Tunnels:       tunnels,
+	}
+}
+
+func NewTunnelInfoWithTransactionID(transactionID string, tunnels ...TunnelDescription) *TunnelInfo {
+	cmd := NewTunnelInfo(tunnels...)
+	cmd.transactionID = transactionID
+	return cmd
+}
+
+func parseTunnelInfo(transactionID string, data []byte) (Command, error) {
+	cmd := NewTunnelInfoWithTransactionID(transactionID)
+	if len(data) == 0 {
+		return cmd, nil
+	}
+
+	for _, entry := range strings.Split(string(data), " ") {
+		fields := strings.Split(entry, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid payload: invalid tunnel entry %q", entry)
+		}
+		tunnelType, err := strconv.ParseUint(fields[1], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload: invalid tunnel type %q", fields[1])
+		}
+		listeners, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload: invalid listeners count %q", fields[2])
+		}
+		cmd.Tunnels = append(cmd.Tunnels, TunnelDescription{
+			Name:      fields[0],
+			Type:      TunnelType(tunnelType),
+			Listeners: listeners,
+		})
+	}
+
+	err := cmd.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tunnel_info command: %s", err)
+	}
+	return cmd, nil
+}
+
+func (cmd *TunnelInfo) Validate() error {
+	for _, tunnel := range cmd.Tunnels {
+		err := tunnel.validate()
+		if err != nil {
+			return fmt.Errorf("tunnel %q: %w", tunnel.Name, err)
+		}
+	}
+	return nil
+}
+
+func (cmd *TunnelInfo) Info() string {
+	return fmt.Sprintf("TUNNEL_INFO(%d)", len(cmd.Tunnels))
+}
+func (cmd *TunnelInfo) TransactionID() string { return cmd.transactionID }
+func (cmd *TunnelInfo) Indicator() byte       { return TunnelInfoIndicator }
+func (cmd *TunnelInfo) Data() []byte {
+	buf := bytes.Buffer{}
+	for i, tunnel := range cmd.Tunnels {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%s:%d:%d", tunnel.Name, tunnel.Type, tunnel.Listeners)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,27 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTunnelInfo_Info(t *testing.T) {
+	assert.Equal(t, "TUNNEL_INFO(1)", NewTunnelInfo(TunnelDescription{Name: "Bidule"}).Info())
+}
+
+func TestTunnelInfo_TransactionID(t *testing.T) {
+	assert.Equal(t, newIDValue, NewTunnelInfo().TransactionID())
+}
+
+func TestTunnelInfo_Indicator(t *testing.T) {
+	assert.Equal(t, TunnelInfoIndicator, NewTunnelInfo().Indicator())
+}
+
+func TestTunnelInfo_Data(t *testing.T) {
+	assert.Empty(t, NewTunnelInfo().Data())
+	assert.Equal(t, []byte("Bidule:0:3 Machin:0:0"), NewTunnelInfo(
+		TunnelDescription{Name: "Bidule", Type: BroadcastTunnel, Listeners: 3},
+		TunnelDescription{Name: "Machin", Type: BroadcastTunnel},
+	).Data())
+}
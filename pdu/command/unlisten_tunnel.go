@@ -0,0 +1,56 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type UnlistenTunnel struct {
+	transactionID string
+
+	Name string
+}
+
+func NewUnlistenTunnel(name string) *UnlistenTunnel {
+	return &UnlistenTunnel{
+		transactionID: newID(),
+		Name:          name,
+	}
+}
+
+func NewUnlistenTunnelWithTransactionID(transactionID, name string) *UnlistenTunnel {
+	cmd := NewUnlistenTunnel(name)
+	cmd.transactionID = transactionID
+	return cmd
+}
+
+func parseUnlistenTunnel(transactionID string, data []byte) (Command, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid payload: missing tunnel name")
+	}
+
+	cmd := NewUnlistenTunnelWithTransactionID(transactionID, string(data))
+	err := cmd.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid unlisten_tunnel command: %s", err)
+	}
+	return cmd, nil
+}
+
+func (cmd *UnlistenTunnel) Validate() error {
+	if !validListenName(cmd.Name) {
+		return fmt.Errorf("invalid name")
+	}
+	return nil
+}
+
+func (cmd *UnlistenTunnel) Info() string {
+	return fmt.Sprintf("UNLISTEN_TUNNEL(%s)", cmd.Name)
+}
+func (cmd *UnlistenTunnel) TransactionID() string { return cmd.transactionID }
+func (cmd *UnlistenTunnel) Indicator() byte       { return UnlistenTunnelIndicator }
+func (cmd *UnlistenTunnel) Data() []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(cmd.Name)
+	return buf.Bytes()
+}
@@ -0,0 +1,23 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnlistenTunnel_Info(t *testing.T) {
+	assert.Equal(t, "UNLISTEN_TUNNEL(Bidule)", NewUnlistenTunnel("Bidule").Info())
+}
+
+func TestUnlistenTunnel_TransactionID(t *testing.T) {
+	assert.Equal(t, newIDValue, NewUnlistenTunnel("Bidule").TransactionID())
+}
+
+func TestUnlistenTunnel_Indicator(t *testing.T) {
+	assert.Equal(t, UnlistenTunnelIndicator, NewUnlistenTunnel("Bidule").Indicator())
+}
+
+func TestUnlistenTunnel_Data(t *testing.T) {
+	assert.Equal(t, []byte("Bidule"), NewUnlistenTunnel("Bidule").Data())
+}
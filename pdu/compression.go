@@ -0,0 +1,107 @@
+package pdu
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// Compression is the algorithm compressing the commands' data, agreed for a connection during the handshake.
+// Both ends of a connection must use the same Compression.
+type Compression byte
+
+const (
+	NoCompression Compression = iota
+	DeflateCompression
+	GzipCompression
+)
+
+// CompressedFlag is set on the indicator of the payloads whose data is compressed.
+// The indicators being ASCII characters, the bit is never set otherwise.
+const CompressedFlag byte = 0x80
+
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case DeflateCompression:
+		return "deflate"
+	case GzipCompression:
+		return "gzip"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(c))
+	}
+}
+
+// NegotiatedCompression returns the Compression of the first compression capability, NoCompression if there is none.
+// The capabilities being the agreed ones (ordered as offered by the client), both ends choose the same Compression.
+func NegotiatedCompression(capabilities []command.Capability) Compression {
+	for _, capability := range capabilities {
+		switch capability {
+		case command.DeflateCompressionCapability:
+			return DeflateCompression
+		case command.GzipCompressionCapability:
+			return GzipCompression
+		}
+	}
+	return NoCompression
+}
+
+// Compress the data.
+func (c Compression) Compress(data []byte) ([]byte, error) {
+	buf := bytes.Buffer{}
+	var writer io.WriteCloser
+	switch c {
+	case DeflateCompression:
+		writer, _ = flate.NewWriter(&buf, flate.DefaultCompression) // Never fails with a valid level
+	case GzipCompression:
+		writer = gzip.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", c)
+	}
+
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress the data. maxSize is the maximum size of the decompressed data in bytes, 0 means unlimited.
+func (c Compression) Decompress(data []byte, maxSize int) ([]byte, error) {
+	var reader io.ReadCloser
+	switch c {
+	case DeflateCompression:
+		reader = flate.NewReader(bytes.NewReader(data))
+	case GzipCompression:
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decompress: %w", err)
+		}
+		reader = gzipReader
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", c)
+	}
+	defer reader.Close()
+
+	var limited io.Reader = reader
+	if maxSize > 0 {
+		limited = io.LimitReader(reader, int64(maxSize)+1)
+	}
+	decompressed, err := io.ReadAll(limited)
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	if maxSize > 0 && len(decompressed) > maxSize {
+		return nil, fmt.Errorf("decompress: %w: decompressed data exceeds %d bytes", ErrFrameTooLarge, maxSize)
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,163 @@
+package pdu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestCompression_String(t *testing.T) {
+	assert.Equal(t, "none", NoCompression.String())
+	assert.Equal(t, "deflate", DeflateCompression.String())
+	assert.Equal(t, "gzip", GzipCompression.String())
+	assert.Equal(t, "unknown(17)", Compression(17).String())
+}
+
+func TestNegotiatedCompression(t *testing.T) {
+	for name, tc := range map[string]struct {
+		capabilities []command.Capability
+		expected     Compression
+	}{
+		"No capability": {
+			capabilities: nil,
+			expected:     NoCompression,
+		},
+		"No compression capability": {
+			capabilities: []command.Capability{command.LengthPrefixedFramingCapability},
+			expected:     NoCompression,
+		},
+		"Deflate": {
+			capabilities: []command.Capability{command.LengthPrefixedFramingCapability, command.DeflateCompressionCapability},
+			expected:     DeflateCompression,
+		},
+		"Gzip": {
+			capabilities: []command.Capability{command.GzipCompressionCapability},
+			expected:     GzipCompression,
+		},
+		"First one": {
+			capabilities: []command.Capability{command.GzipCompressionCapability, command.DeflateCompressionCapability},
+			expected:     GzipCompression,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, NegotiatedCompression(tc.capabilities))
+		})
+	}
+}
+
+func TestCompression_Compress_Decompress(t *testing.T) {
+	data := bytes.Repeat([]byte("This is a message. "), 100)
+	for _, compression := range []Compression{DeflateCompression, GzipCompression} {
+		t.Run(compression.String(), func(t *testing.T) {
+			compressed, err := compression.Compress(data)
+			require.NoError(t, err)
+			assert.Less(t, len(compressed), len(data))
+
+			decompressed, err := compression.Decompress(compressed, len(data))
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+
+			_, err = compression.Decompress(compressed, len(data)-1)
+			assert.ErrorIs(t, err, ErrFrameTooLarge)
+
+			decompressed, err = compression.Decompress(compressed, 0)
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+		})
+	}
+}
+
+func TestCompression_Decompress_Invalid(t *testing.T) {
+	_, err := DeflateCompression.Decompress([]byte("not compressed"), 0)
+	assert.Error(t, err)
+	_, err = GzipCompression.Decompress([]byte("not compressed"), 0)
+	assert.Error(t, err)
+}
+
+func TestCompression_Unsupported(t *testing.T) {
+	_, err := NoCompression.Compress([]byte("data"))
+	assert.EqualError(t, err, "unsupported compression none")
+	_, err = Compression(17).Decompress([]byte("data"), 0)
+	assert.EqualError(t, err, "unsupported compression unknown(17)")
+}
+
+func TestFraming_MarshalCompressed(t *testing.T) {
+	compressible := FakeCommand{id: "abcd1234", indicator: '=', data: bytes.Repeat([]byte("data"), 100)}
+
+	for name, tc := range map[string]struct {
+		framing          Framing
+		compression      Compression
+		threshold        int
+		cmd              FakeCommand
+		expectCompressed bool
+	}{
+		"Compressed": {
+			framing:          LengthPrefixedFraming,
+			compression:      DeflateCompression,
+			threshold:        100,
+			cmd:              compressible,
+			expectCompressed: true,
+		},
+		"Below threshold": {
+			framing:     LengthPrefixedFraming,
+			compression: DeflateCompression,
+			threshold:   1000,
+			cmd:         compressible,
+		},
+		"No compression": {
+			framing:     LengthPrefixedFraming,
+			compression: NoCompression,
+			cmd:         compressible,
+		},
+		"Delimited framing": {
+			framing:     DelimitedFraming,
+			compression: GzipCompression,
+			cmd:         compressible,
+		},
+		"Not smaller once compressed": {
+			framing:     LengthPrefixedFraming,
+			compression: GzipCompression,
+			cmd:         FakeCommand{id: "abcd1234", indicator: '=', data: []byte("data")},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			payload, err := tc.framing.MarshalCompressed(tc.cmd, tc.compression, tc.threshold)
+			require.NoError(t, err)
+
+			if !tc.expectCompressed {
+				assert.Equal(t, tc.framing.Marshal(tc.cmd), payload)
+				return
+			}
+			assert.Equal(t, tc.cmd.indicator|CompressedFlag, payload[0])
+			assert.Less(t, len(payload), len(tc.framing.Marshal(tc.cmd)))
+		})
+	}
+}
+
+func TestFraming_UnmarshalCompressed(t *testing.T) {
+	for _, compression := range []Compression{DeflateCompression, GzipCompression} {
+		t.Run(compression.String(), func(t *testing.T) {
+			cmd := command.NewPublishMessageWithTransactionID("abcd1234", "Bidule", bytes.Repeat([]byte("message "), 100))
+
+			payload, err := LengthPrefixedFraming.MarshalCompressed(cmd, compression, 0)
+			require.NoError(t, err)
+			require.Equal(t, command.PublishMessageIndicator|CompressedFlag, payload[0])
+
+			unmarshalled, err := LengthPrefixedFraming.UnmarshalCompressed(payload, compression, 0)
+			require.NoError(t, err)
+			assert.Equal(t, cmd, unmarshalled)
+
+			_, err = LengthPrefixedFraming.UnmarshalCompressed(payload, compression, 100)
+			assert.ErrorIs(t, err, ErrFrameTooLarge)
+
+			// Not compressed payloads are unmarshalled as is.
+			unmarshalled, err = LengthPrefixedFraming.UnmarshalCompressed(LengthPrefixedFraming.Marshal(cmd), compression, 100)
+			require.NoError(t, err)
+			assert.Equal(t, cmd, unmarshalled)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package pdu
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync/atomic"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// ErrFrameTooLarge is returned when a payload exceeds the max frame size.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// DefaultMaxFrameSize is the maximum payload size (in bytes) read when no MaxFrameSize is given.
+// It prevents a declared length from allocating an unbounded amount of memory.
+const DefaultMaxFrameSize = 16 * 1024 * 1024
+
+type DecoderOption struct {
+	// Framing used to split the payloads.
+	Framing Framing
+
+	// MaxFrameSize is the maximum payload size in bytes. 0 means DefaultMaxFrameSize.
+	MaxFrameSize int
+}
+
+// Decoder reads payloads from an io.Reader and decodes them into commands one by one.
+// It's not safe to read concurrently, but the framing can be changed at any time.
+type Decoder struct {
+	reader       *bufio.Reader
+	framing      atomic.Uint32
+	maxFrameSize int
+}
+
+// NewDecoder creates a Decoder reading from r. opts can be nil.
+func NewDecoder(r io.Reader, opts *DecoderOption) *Decoder {
+	if opts == nil {
+		opts = &DecoderOption{}
+	}
+	decoder := &Decoder{
+		reader:       bufio.NewReader(r),
+		maxFrameSize: opts.MaxFrameSize,
+	}
+	if decoder.maxFrameSize <= 0 {
+		decoder.maxFrameSize = DefaultMaxFrameSize
+	}
+	decoder.framing.Store(uint32(opts.Framing))
+	return decoder
+}
+
+// SetFraming changes the framing used to read the next payloads.
+func (d *Decoder) SetFraming(framing Framing) {
+	d.framing.Store(uint32(framing))
+}
+
+// Framing returns the framing used to read the next payload.
+func (d *Decoder) Framing() Framing {
+	return Framing(d.framing.Load())
+}
+
+// Decode reads the next payload and decodes it into a command.
+// The errors of the io.Reader are returned as is (io.EOF when there is no more payload).
+func (d *Decoder) Decode() (command.Command, error) {
+	framing := d.Framing()
+	payload, err := d.readPayload(framing)
+	if err != nil {
+		return nil, err
+	}
+	return framing.Unmarshal(payload)
+}
+
+// ReadPayload reads the next whole payload without decoding it.
+// The returned payload can be given to the Unmarshal method of the decoder's Framing.
+func (d *Decoder) ReadPayload() ([]byte, error) {
+	return d.readPayload(d.Framing())
+}
+
+func (d *Decoder) readPayload(framing Framing) ([]byte, error) {
+	switch framing {
+	case DelimitedFraming:
+		return d.readDelimitedPayload()
+	case LengthPrefixedFraming:
+		return d.readLengthPrefixedPayload()
+	default:
+		return nil, fmt.Errorf("unsupported framing %s", framing)
+	}
+}
+
+func (d *Decoder) readDelimitedPayload() ([]byte, error) {
+	var payload []byte
+	for {
+		chunk, err := d.reader.ReadSlice(Delimiter)
+		payload = append(payload, chunk...)
+		sizeErr := d.checkSize(len(payload))
+		if sizeErr != nil {
+			return nil, sizeErr
+		}
+		switch {
+		case err == nil:
+			return payload, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		default:
+			return nil, err
+		}
+	}
+}
+
+func (d *Decoder) readLengthPrefixedPayload() ([]byte, error) {
+	header := make([]byte, headerLength)
+	_, err := io.ReadFull(d.reader, header)
+	if err != nil {
+		return nil, err
+	}
+	length, err := binary.ReadUvarint(d.reader)
+	if err != nil {
+		return nil, fmt.Errorf("read length prefix: %w", err)
+	}
+
+	payload := binary.AppendUvarint(header, length)
+	if length > uint64(math.MaxInt-len(payload)) {
+		return nil, fmt.Errorf("%w: declared length %d", ErrFrameTooLarge, length)
+	}
+	err = d.checkSize(len(payload) + int(length))
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, length)
+	_, err = io.ReadFull(d.reader, data)
+	if err != nil {
+		return nil, err
+	}
+	return append(payload, data...), nil
+}
+
+func (d *Decoder) checkSize(size int) error {
+	if size > d.maxFrameSize {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrFrameTooLarge, size, d.maxFrameSize)
+	}
+	return nil
+}
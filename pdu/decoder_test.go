@@ -0,0 +1,148 @@
+package pdu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	for name, tc := range map[string]struct {
+		framing Framing
+		message []byte
+	}{
+		"Delimited": {
+			framing: DelimitedFraming,
+			message: []byte("first message"),
+		},
+		"Length prefixed": {
+			framing: LengthPrefixedFraming,
+			message: []byte("first\nmessage"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			first := command.NewPublishMessageWithTransactionID("abcd1234", "Bidule", tc.message)
+			second := command.NewAckWithTransactionID("efgh5678")
+			stream := append(tc.framing.Marshal(first), tc.framing.Marshal(second)...)
+
+			decoder := NewDecoder(bytes.NewReader(stream), &DecoderOption{Framing: tc.framing})
+
+			cmd, err := decoder.Decode()
+			require.NoError(t, err)
+			assert.Equal(t, first, cmd)
+
+			cmd, err = decoder.Decode()
+			require.NoError(t, err)
+			assert.Equal(t, second, cmd)
+
+			_, err = decoder.Decode()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestDecoder_Decode_InvalidPayload(t *testing.T) {
+	decoder := NewDecoder(bytes.NewReader([]byte("$abcd1234\n")), nil)
+	_, err := decoder.Decode()
+	assert.EqualError(t, err, "invalid command indicator: unknown 0x24")
+}
+
+func TestDecoder_ReadPayload(t *testing.T) {
+	for name, tc := range map[string]struct {
+		framing Framing
+		data    []byte
+	}{
+		"Delimited": {
+			framing: DelimitedFraming,
+			data:    []byte("first line"),
+		},
+		"Length prefixed": {
+			framing: LengthPrefixedFraming,
+			data:    []byte("first\nline"),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			first := tc.framing.Marshal(FakeCommand{id: "abcd1234", indicator: '=', data: tc.data})
+			second := tc.framing.Marshal(FakeCommand{id: "efgh5678", indicator: '=', data: []byte{}})
+			decoder := NewDecoder(bytes.NewReader(append(append([]byte{}, first...), second...)), &DecoderOption{Framing: tc.framing})
+
+			payload, err := decoder.ReadPayload()
+			require.NoError(t, err)
+			assert.Equal(t, first, payload)
+
+			payload, err = decoder.ReadPayload()
+			require.NoError(t, err)
+			assert.Equal(t, second, payload)
+
+			_, err = decoder.ReadPayload()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestDecoder_ReadPayload_Truncated(t *testing.T) {
+	decoder := NewDecoder(bytes.NewReader([]byte("=abcd1234\x05da")), &DecoderOption{Framing: LengthPrefixedFraming})
+	_, err := decoder.ReadPayload()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestDecoder_ReadPayload_FrameTooLarge(t *testing.T) {
+	for name, tc := range map[string]struct {
+		framing              Framing
+		payload              []byte
+		expectedErrorMessage string
+	}{
+		"Delimited": {
+			framing:              DelimitedFraming,
+			payload:              append(bytes.Repeat([]byte("a"), 5000), '\n'),
+			expectedErrorMessage: "frame too large: 4096 bytes exceeds 20",
+		},
+		"Length prefixed": {
+			framing:              LengthPrefixedFraming,
+			payload:              []byte("=abcd1234\x20"),
+			expectedErrorMessage: "frame too large: 42 bytes exceeds 20",
+		},
+		"Length prefixed - Overflow": {
+			framing:              LengthPrefixedFraming,
+			payload:              []byte("=abcd1234\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01"),
+			expectedErrorMessage: "frame too large: declared length 18446744073709551615",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			decoder := NewDecoder(bytes.NewReader(tc.payload), &DecoderOption{Framing: tc.framing, MaxFrameSize: 20})
+			_, err := decoder.ReadPayload()
+			assert.ErrorIs(t, err, ErrFrameTooLarge)
+			assert.EqualError(t, err, tc.expectedErrorMessage)
+		})
+	}
+}
+
+func TestDecoder_ReadPayload_DefaultMaxFrameSize(t *testing.T) {
+	payload := binary.AppendUvarint([]byte("=abcd1234"), 1<<50)
+	decoder := NewDecoder(bytes.NewReader(payload), &DecoderOption{Framing: LengthPrefixedFraming})
+	_, err := decoder.ReadPayload()
+	assert.ErrorIs(t, err, ErrFrameTooLarge)
+}
+
+func TestDecoder_SetFraming(t *testing.T) {
+	first := DelimitedFraming.Marshal(command.NewAckWithTransactionID("abcd1234"))
+	second := LengthPrefixedFraming.Marshal(command.NewAckWithTransactionID("efgh5678"))
+	decoder := NewDecoder(bytes.NewReader(append(first, second...)), nil)
+
+	cmd, err := decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, "abcd1234", cmd.TransactionID())
+
+	decoder.SetFraming(LengthPrefixedFraming)
+	assert.Equal(t, LengthPrefixedFraming, decoder.Framing())
+
+	cmd, err = decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, "efgh5678", cmd.TransactionID())
+}
@@ -0,0 +1,68 @@
+package pdu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+type EncoderOption struct {
+	// Framing used to write the payloads.
+	Framing Framing
+
+	// MaxFrameSize is the maximum payload size in bytes. 0 means unlimited.
+	MaxFrameSize int
+}
+
+// Encoder encodes commands and writes them to an io.Writer.
+// Payloads are buffered until Flush is called (or the buffer is full).
+// It's not safe to encode concurrently, but the framing can be changed at any time.
+type Encoder struct {
+	writer       *bufio.Writer
+	framing      atomic.Uint32
+	maxFrameSize int
+}
+
+// NewEncoder creates an Encoder writing to w. opts can be nil.
+func NewEncoder(w io.Writer, opts *EncoderOption) *Encoder {
+	if opts == nil {
+		opts = &EncoderOption{}
+	}
+	encoder := &Encoder{
+		writer:       bufio.NewWriter(w),
+		maxFrameSize: opts.MaxFrameSize,
+	}
+	encoder.framing.Store(uint32(opts.Framing))
+	return encoder
+}
+
+// SetFraming changes the framing used to write the next payloads.
+func (e *Encoder) SetFraming(framing Framing) {
+	e.framing.Store(uint32(framing))
+}
+
+// Encode writes the command's payload to the buffer.
+// Returns an error if the command cannot be carried by the framing or if it exceeds the max frame size.
+func (e *Encoder) Encode(cmd command.Command) error {
+	framing := Framing(e.framing.Load())
+	err := framing.Check(cmd)
+	if err != nil {
+		return err
+	}
+
+	payload := framing.Marshal(cmd)
+	if e.maxFrameSize > 0 && len(payload) > e.maxFrameSize {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrFrameTooLarge, len(payload), e.maxFrameSize)
+	}
+
+	_, err = e.writer.Write(payload)
+	return err
+}
+
+// Flush writes the buffered payloads to the underlying io.Writer.
+func (e *Encoder) Flush() error {
+	return e.writer.Flush()
+}
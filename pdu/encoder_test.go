@@ -0,0 +1,65 @@
+package pdu
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestEncoder_Encode(t *testing.T) {
+	buf := bytes.Buffer{}
+	encoder := NewEncoder(&buf, nil)
+
+	ack := command.NewAckWithTransactionID("abcd1234")
+	message := command.NewPublishMessageWithTransactionID("efgh5678", "Bidule", []byte("Multi\nline"))
+
+	err := encoder.Encode(ack)
+	require.NoError(t, err)
+	assert.Empty(t, buf.Bytes(), "Payload should be buffered until flush")
+
+	err = encoder.Encode(message)
+	assert.EqualError(t, err, `data contains the delimiter '\n', length_prefixed framing is required`)
+
+	encoder.SetFraming(LengthPrefixedFraming)
+	err = encoder.Encode(message)
+	require.NoError(t, err)
+
+	err = encoder.Flush()
+	require.NoError(t, err)
+	assert.Equal(t, append(DelimitedFraming.Marshal(ack), LengthPrefixedFraming.Marshal(message)...), buf.Bytes())
+}
+
+func TestEncoder_Encode_FrameTooLarge(t *testing.T) {
+	buf := bytes.Buffer{}
+	encoder := NewEncoder(&buf, &EncoderOption{Framing: LengthPrefixedFraming, MaxFrameSize: 20})
+
+	err := encoder.Encode(command.NewPublishMessageWithTransactionID("abcd1234", "Bidule", []byte("Mon super message")))
+	assert.ErrorIs(t, err, ErrFrameTooLarge)
+	assert.EqualError(t, err, "frame too large: 34 bytes exceeds 20")
+}
+
+func TestEncoderDecoder(t *testing.T) {
+	buf := bytes.Buffer{}
+	encoder := NewEncoder(&buf, &EncoderOption{Framing: LengthPrefixedFraming})
+	decoder := NewDecoder(&buf, &DecoderOption{Framing: LengthPrefixedFraming})
+
+	commands := []command.Command{
+		command.NewAckWithTransactionID("abcd1234"),
+		command.NewPublishMessageWithTransactionID("efgh5678", "Bidule", []byte{0x00, '\n', 0xff}),
+		command.NewListenTunnelWithTransactionID("ijkl9012", "Bidule"),
+	}
+	for _, cmd := range commands {
+		require.NoError(t, encoder.Encode(cmd))
+	}
+	require.NoError(t, encoder.Flush())
+
+	for _, expected := range commands {
+		cmd, err := decoder.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, expected, cmd)
+	}
+}
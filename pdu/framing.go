@@ -0,0 +1,158 @@
+package pdu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/codingLayce/tunnel.go/id"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// Framing determines how payloads are delimited on the wire.
+// Both ends of a connection must use the same Framing.
+type Framing byte
+
+const (
+	// DelimitedFraming terminates each payload with the Delimiter.
+	// The command's data cannot contain the Delimiter.
+	DelimitedFraming Framing = iota
+	// LengthPrefixedFraming writes the data length (uvarint) between the transaction id and the data.
+	// The command's data can contain any byte.
+	LengthPrefixedFraming
+)
+
+const headerLength = 9 // 1 byte indicator + 8 bytes transactionID
+
+func (f Framing) String() string {
+	switch f {
+	case DelimitedFraming:
+		return "delimited"
+	case LengthPrefixedFraming:
+		return "length_prefixed"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(f))
+	}
+}
+
+// Marshal the command to a payload following the Framing.
+func (f Framing) Marshal(cmd command.Command) []byte {
+	return f.marshal(cmd.Indicator(), cmd.TransactionID(), cmd.Data())
+}
+
+// MarshalCompressed marshals the command to a payload following the Framing,
+// its data being compressed when it's at least threshold bytes long and smaller once compressed.
+// The indicator of a compressed payload has the CompressedFlag. Only the LengthPrefixedFraming can carry compressed data.
+func (f Framing) MarshalCompressed(cmd command.Command, compression Compression, threshold int) ([]byte, error) {
+	data := cmd.Data()
+	if compression == NoCompression || f != LengthPrefixedFraming || len(data) < threshold {
+		return f.marshal(cmd.Indicator(), cmd.TransactionID(), data), nil
+	}
+
+	compressed, err := compression.Compress(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) >= len(data) {
+		return f.marshal(cmd.Indicator(), cmd.TransactionID(), data), nil
+	}
+	return f.marshal(cmd.Indicator()|CompressedFlag, cmd.TransactionID(), compressed), nil
+}
+
+func (f Framing) marshal(indicator byte, transactionID string, data []byte) []byte {
+	buf := bytes.Buffer{}
+	buf.WriteByte(indicator)
+	buf.WriteString(transactionID)
+	switch f {
+	case LengthPrefixedFraming:
+		buf.Write(binary.AppendUvarint(nil, uint64(len(data))))
+		buf.Write(data)
+	default:
+		buf.Write(data)
+		buf.WriteByte(Delimiter)
+	}
+	return buf.Bytes()
+}
+
+// Check determines if the command's data can be carried by the Framing.
+func (f Framing) Check(cmd command.Command) error {
+	if f == DelimitedFraming && bytes.IndexByte(cmd.Data(), Delimiter) != -1 {
+		return fmt.Errorf("data contains the delimiter %q, %s framing is required", Delimiter, LengthPrefixedFraming)
+	}
+	return nil
+}
+
+// Unmarshal the payload following the Framing to the corresponding command.
+func (f Framing) Unmarshal(payload []byte) (command.Command, error) {
+	indicator, transactionID, data, err := f.split(payload)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommand(indicator, transactionID, data)
+}
+
+// UnmarshalCompressed unmarshals the payload following the Framing to the corresponding command,
+// decompressing the data of the payloads having the CompressedFlag.
+// maxDataSize is the maximum size of the decompressed data in bytes, 0 means unlimited.
+func (f Framing) UnmarshalCompressed(payload []byte, compression Compression, maxDataSize int) (command.Command, error) {
+	indicator, transactionID, data, err := f.split(payload)
+	if err != nil {
+		return nil, err
+	}
+	if indicator&CompressedFlag != 0 {
+		indicator &^= CompressedFlag
+		data, err = compression.Decompress(data, maxDataSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseCommand(indicator, transactionID, data)
+}
+
+// Header returns the indicator (without the CompressedFlag) and the transaction id of the payload, whatever its Framing.
+// ok is false when the payload is too short or its transaction id is invalid.
+func Header(payload []byte) (indicator byte, transactionID string, ok bool) {
+	if len(payload) < headerLength {
+		return 0, "", false
+	}
+	transactionID = string(payload[1:headerLength])
+	if !id.IsValid(transactionID) {
+		return 0, "", false
+	}
+	return payload[0] &^ CompressedFlag, transactionID, true
+}
+
+func (f Framing) split(payload []byte) (byte, string, []byte, error) {
+	// Returns the indicator, the transaction id and the data of the payload.
+	if len(payload) < headerLength+1 { // 1 byte delimiter or at least 1 byte length
+		return 0, "", nil, fmt.Errorf("invalid payload length: cannot be less than 10 bytes")
+	}
+
+	var data []byte
+	switch f {
+	case DelimitedFraming:
+		if payload[len(payload)-1] != Delimiter {
+			return 0, "", nil, fmt.Errorf("invalid payload delimiter %q, expected %q", payload[len(payload)-1], Delimiter)
+		}
+		data = payload[headerLength : len(payload)-1]
+	case LengthPrefixedFraming:
+		length, n := binary.Uvarint(payload[headerLength:])
+		if n <= 0 {
+			return 0, "", nil, fmt.Errorf("invalid payload length prefix")
+		}
+		data = payload[headerLength+n:]
+		if uint64(len(data)) != length {
+			return 0, "", nil, fmt.Errorf("invalid payload length: expected %d bytes of data, got %d", length, len(data))
+		}
+	default:
+		return 0, "", nil, fmt.Errorf("unsupported framing %s", f)
+	}
+
+	indicator := payload[0]
+	transactionID := string(payload[1:headerLength])
+	if !id.IsValid(transactionID) {
+		return 0, "", nil, fmt.Errorf("invalid transaction id")
+	}
+
+	return indicator, transactionID, data, nil
+}
@@ -0,0 +1,98 @@
+package pdu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+	"github.com/codingLayce/tunnel.go/test-helper/mock"
+)
+
+func TestFraming_Marshal(t *testing.T) {
+	cmd := FakeCommand{
+		id:        "abcd1234",
+		indicator: '=',
+		data:      []byte("da\nta"),
+	}
+	assert.Equal(t, []byte("=abcd1234da\nta\n"), DelimitedFraming.Marshal(cmd))
+	assert.Equal(t, []byte("=abcd1234\x05da\nta"), LengthPrefixedFraming.Marshal(cmd))
+}
+
+func TestFraming_Unmarshal_LengthPrefixed(t *testing.T) {
+	mock.Do(t, &parseCommand, func(indicator byte, transactionID string, data []byte) (command.Command, error) {
+		assert.Equal(t, byte('='), indicator)
+		assert.Equal(t, "toto1234", transactionID)
+		assert.Equal(t, []byte("My\nData"), data)
+		return nil, nil
+	})
+
+	cmd, err := LengthPrefixedFraming.Unmarshal([]byte("=toto1234\x07My\nData"))
+	require.NoError(t, err)
+	require.Nil(t, cmd)
+}
+
+func TestFraming_Unmarshal_LengthPrefixed_Error(t *testing.T) {
+	for name, tc := range map[string]struct {
+		payload              []byte
+		expectedErrorMessage string
+	}{
+		"Payload without length": {
+			payload:              []byte("=abcd1234"),
+			expectedErrorMessage: "invalid payload length: cannot be less than 10 bytes",
+		},
+		"Payload with invalid length prefix": {
+			payload:              []byte("=abcd1234\xff"),
+			expectedErrorMessage: "invalid payload length prefix",
+		},
+		"Payload shorter than length": {
+			payload:              []byte("=abcd1234\x05data"),
+			expectedErrorMessage: "invalid payload length: expected 5 bytes of data, got 4",
+		},
+		"Payload longer than length": {
+			payload:              []byte("=abcd1234\x03data"),
+			expectedErrorMessage: "invalid payload length: expected 3 bytes of data, got 4",
+		},
+		"Payload with invalid transaction id": {
+			payload:              []byte("=abcd123_\x00"),
+			expectedErrorMessage: "invalid transaction id",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmd, err := LengthPrefixedFraming.Unmarshal(tc.payload)
+			assert.EqualError(t, err, tc.expectedErrorMessage)
+			assert.Nil(t, cmd)
+		})
+	}
+}
+
+func TestFraming_Unmarshal_Unsupported(t *testing.T) {
+	cmd, err := Framing(17).Unmarshal([]byte("=abcd1234data\n"))
+	assert.EqualError(t, err, "unsupported framing unknown(17)")
+	assert.Nil(t, cmd)
+}
+
+func TestHeader(t *testing.T) {
+	indicator, transactionID, ok := Header([]byte("=abcd1234da\nta\n"))
+	require.True(t, ok)
+	assert.Equal(t, byte('='), indicator)
+	assert.Equal(t, "abcd1234", transactionID)
+
+	indicator, transactionID, ok = Header([]byte{'=' | CompressedFlag, 'a', 'b', 'c', 'd', '1', '2', '3', '4', 0x01, 0xff})
+	require.True(t, ok)
+	assert.Equal(t, byte('='), indicator)
+	assert.Equal(t, "abcd1234", transactionID)
+
+	_, _, ok = Header([]byte("=abcd\n"))
+	assert.False(t, ok)
+	_, _, ok = Header([]byte("=ab$%1234data\n"))
+	assert.False(t, ok)
+}
+
+func TestFraming_Check(t *testing.T) {
+	cmd := FakeCommand{id: "abcd1234", indicator: '=', data: []byte("da\nta")}
+	assert.EqualError(t, DelimitedFraming.Check(cmd), `data contains the delimiter '\n', length_prefixed framing is required`)
+	assert.NoError(t, LengthPrefixedFraming.Check(cmd))
+	assert.NoError(t, DelimitedFraming.Check(FakeCommand{id: "abcd1234", indicator: '=', data: []byte("data")}))
+}
@@ -0,0 +1,116 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// newReconnectingTCPClient returns a TestTCPClient whose reconnections complete with the errors written to the channel.
+func newReconnectingTCPClient(t *testing.T) (*TestTCPClient, chan<- error) {
+	tcpClient := newTestTCPClient()
+	reconnect := make(chan error)
+	connected := atomic.Bool{}
+	tcpClient.connect = func() error {
+		if connected.CompareAndSwap(false, true) {
+			return nil
+		}
+		return <-reconnect
+	}
+	mockNewTCPClient(t, tcpClient)
+	return tcpClient, reconnect
+}
+
+func TestClient_PendingFailsOnConnectionLost(t *testing.T) {
+	tcpClient, reconnect := newReconnectingTCPClient(t)
+
+	cl, err := Connect("", WithRetryPolicy(ConstantBackoff(time.Millisecond)))
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	start := time.Now()
+	done := make(chan error)
+	go func() {
+		done <- cl.CreateBTunnel("MyTunnel")
+	}()
+	receiveCommand(t, tcpClient)
+	time.Sleep(20 * time.Millisecond) // Let time to the client to wait for the response
+
+	// Lose the connection before responding
+	tcpClient.disconnect()
+
+	select {
+	case err = <-done:
+		assert.ErrorIs(t, err, ErrConnectionLost)
+		assert.Less(t, time.Since(start), time.Second)
+	case <-time.After(time.Second):
+		assert.FailNow(t, "CreateBTunnel should have failed")
+	}
+	reconnect <- nil
+}
+
+func TestClient_NotConnectedWhileReconnecting(t *testing.T) {
+	tcpClient, reconnect := newReconnectingTCPClient(t)
+
+	cl, err := Connect("", WithRetryPolicy(ConstantBackoff(time.Millisecond)))
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	tcpClient.disconnect()
+	assert.Eventually(t, func() bool { return cl.State() == StateReconnecting }, time.Second, 5*time.Millisecond)
+
+	err = cl.PublishMessage("MyTunnel", "Mon super message !")
+	assert.ErrorIs(t, err, ErrNotConnected)
+	reconnect <- nil
+}
+
+func TestClient_WaitForReconnect(t *testing.T) {
+	tcpClient, reconnect := newReconnectingTCPClient(t)
+
+	cl, err := Connect("", WithRetryPolicy(ConstantBackoff(time.Millisecond)), WithWaitForReconnect(true))
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	tcpClient.disconnect()
+	assert.Eventually(t, func() bool { return cl.State() == StateReconnecting }, time.Second, 5*time.Millisecond)
+
+	published := make(chan error)
+	go func() {
+		published <- cl.PublishMessage("MyTunnel", "Mon super message !")
+	}()
+
+	// The deadline of the context bounds the wait
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = cl.PublishMessageContext(ctx, "MyTunnel", "Trop tard")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	select {
+	case err = <-published:
+		assert.FailNow(t, "PublishMessage should wait for the reconnection", err)
+	default:
+	}
+
+	// The first attempt fails, the second one succeeds
+	reconnect <- errors.New("unreachable")
+	reconnect <- nil
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		publish, ok := cmd.(*command.PublishMessage)
+		require.True(t, ok, cmd.Info())
+		assert.Equal(t, "Mon super message !", string(publish.Message))
+		go tcpClient.callOnPayload(pdu.Marshal(ack(cmd)))
+	case <-time.After(time.Second):
+		assert.FailNow(t, "Server should have received the message")
+	}
+	assert.NoError(t, <-published)
+}
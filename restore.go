@@ -0,0 +1,53 @@
+package tunnel
+
+import (
+	"errors"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func (c *Client) restore() {
+	// Invoked after each reconnection: the server may have lost the Tunnels and has forgotten the listeners of the
+	// previous connection, so the declared Tunnels are created again and the active Subscriptions listened again.
+	// The failures are reported to Hooks.OnRestoreError.
+	tunnels := make(map[string]command.TunnelType)
+	c.tunnels.Foreach(func(name string, tunnelType command.TunnelType) {
+		tunnels[name] = tunnelType
+	})
+	for name, tunnelType := range tunnels {
+		cmd := command.NewCreateTunnelWithTransactionID(c.newTransactionID(), name)
+		cmd.Type = tunnelType
+		err := ackResult(c.exchange(c.ctx, cmd, c.ackTimeout, c.transmit))
+		if err != nil && !errors.Is(err, ErrTunnelExists) {
+			c.restoreFailed(name, err)
+		}
+	}
+
+	// Collected first, a Subscription refused by the server is ended.
+	var subs []*Subscription
+	c.listeners.Foreach(func(_ string, sub *Subscription) {
+		subs = append(subs, sub)
+	})
+	for _, sub := range subs {
+		cmd := command.NewListenTunnelWithTransactionID(c.newTransactionID(), sub.tunnelName)
+		err := ackResult(c.exchange(c.ctx, cmd, c.ackTimeout, c.transmit))
+		if err != nil {
+			if errors.Is(err, ErrNack) { // Otherwise the connection is lost again, the next reconnection retries.
+				sub.end(err)
+			}
+			c.restoreFailed(sub.tunnelName, err)
+		}
+	}
+
+	c.Logger.Info("Restored Tunnels and Subscriptions", "tunnels", len(tunnels), "subscriptions", len(subs))
+}
+
+func (c *Client) restoreFailed(tunnelName string, err error) {
+	if c.ctx.Err() != nil {
+		return // Stopped meanwhile
+	}
+	c.Logger.Error("Cannot restore Tunnel", "tunnel_name", tunnelName, "error", err)
+	if c.hooks.OnRestoreError != nil {
+		c.hooks.OnRestoreError(tunnelName, err)
+	}
+}
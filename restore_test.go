@@ -0,0 +1,155 @@
+package tunnel
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestClient_RestoreAfterReconnect(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.QueueTunnelCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("", WithRetryPolicy(ConstantBackoff(time.Millisecond)), WithHooks(Hooks{
+		OnRestoreError: func(tunnelName string, err error) {
+			assert.Fail(t, "Restore should have succeeded", "tunnel_name", tunnelName, "error", err)
+		},
+	}))
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	require.NoError(t, cl.CreateQTunnel("Jobs"))
+	replyNext(t, tcpClient, nack(command.NackTunnelExists))
+	require.ErrorIs(t, cl.CreateBTunnel("Events"), ErrTunnelExists)
+	replyNext(t, tcpClient, ack)
+	require.NoError(t, cl.CreateBTunnel("Deleted"))
+	replyNext(t, tcpClient, ack)
+	require.NoError(t, cl.DeleteTunnel("Deleted"))
+	received := make(chan string, 1)
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("Events", func(msg string) { received <- msg })
+	require.NoError(t, err)
+
+	// Lose the connection, the server has lost the Tunnels except Events
+	tcpClient.disconnect()
+
+	created := make(map[string]command.TunnelType)
+	for range 2 {
+		cmd := receiveCommand(t, tcpClient)
+		createTunnel, ok := cmd.(*command.CreateTunnel)
+		require.True(t, ok, cmd.Info())
+		created[createTunnel.Name] = createTunnel.Type
+		response := ack(cmd)
+		if createTunnel.Name == "Events" {
+			response = nack(command.NackTunnelExists)(cmd)
+		}
+		tcpClient.callOnPayload(pdu.Marshal(response))
+	}
+	assert.Equal(t, map[string]command.TunnelType{"Jobs": command.QueueTunnel, "Events": command.BroadcastTunnel}, created)
+
+	cmd := receiveCommand(t, tcpClient)
+	listenTunnel, ok := cmd.(*command.ListenTunnel)
+	require.True(t, ok, cmd.Info())
+	assert.Equal(t, "Events", listenTunnel.Name)
+	tcpClient.callOnPayload(pdu.Marshal(ack(cmd)))
+
+	assert.Eventually(t, func() bool { return cl.State() == StateConnected }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, sub.Err())
+
+	// The Subscription receives the messages again
+	go tcpClient.callOnPayload(pdu.Marshal(command.NewReceiveMessage("Events", []byte("Back !"))))
+	select {
+	case msg := <-received:
+		assert.Equal(t, "Back !", msg)
+	case <-time.After(time.Second):
+		assert.FailNow(t, "Message should have been received")
+	}
+	_, ok = receiveCommand(t, tcpClient).(*command.Ack)
+	assert.True(t, ok)
+}
+
+func TestClient_RestoreError(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	var failures []string
+	var failureErrs []error
+	mtx := sync.Mutex{}
+	cl, err := Connect("", WithRetryPolicy(ConstantBackoff(time.Millisecond)), WithHooks(Hooks{
+		OnRestoreError: func(tunnelName string, err error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			failures = append(failures, tunnelName)
+			failureErrs = append(failureErrs, err)
+		},
+	}))
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("Gone", func(_ string) {})
+	require.NoError(t, err)
+
+	// Lose the connection, the Tunnel has been deleted meanwhile
+	tcpClient.disconnect()
+
+	cmd := receiveCommand(t, tcpClient)
+	_, ok := cmd.(*command.ListenTunnel)
+	require.True(t, ok, cmd.Info())
+	tcpClient.callOnPayload(pdu.Marshal(nack(command.NackTunnelNotFound)(cmd)))
+
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		assert.FailNow(t, "Subscription should have ended")
+	}
+	assert.ErrorIs(t, sub.Err(), ErrTunnelNotFound)
+	assert.Eventually(t, func() bool { return cl.State() == StateConnected }, time.Second, 5*time.Millisecond)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, []string{"Gone"}, failures)
+	assert.ErrorIs(t, failureErrs[0], ErrTunnelNotFound)
+}
+
+func TestClient_OperationsWaitForRestore(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("", WithWaitForReconnect(true), WithRetryPolicy(ConstantBackoff(time.Millisecond)))
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	require.NoError(t, cl.CreateBTunnel("Jobs"))
+
+	// Lose the connection, the restoration is pending
+	tcpClient.disconnect()
+	restore := receiveCommand(t, tcpClient)
+	_, ok := restore.(*command.CreateTunnel)
+	require.True(t, ok, restore.Info())
+
+	published := make(chan error, 1)
+	go func() {
+		published <- cl.PublishMessage("Jobs", "Mon super message !")
+	}()
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		assert.FailNow(t, "The operations must wait for the restoration", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tcpClient.callOnPayload(pdu.Marshal(ack(restore)))
+	cmd := receiveCommand(t, tcpClient)
+	_, ok = cmd.(*command.PublishMessage)
+	require.True(t, ok, cmd.Info())
+	tcpClient.callOnPayload(pdu.Marshal(ack(cmd)))
+	assert.NoError(t, <-published)
+}
@@ -0,0 +1,71 @@
+package tunnel
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy decides the delays between the reconnection attempts, the first attempt being immediate.
+type RetryPolicy interface {
+	// Delay returns the duration to wait after the given failed attempt (starting at 1) before the next one.
+	// Returns false to give up reconnecting, the Client is then stopped with ErrReconnectGaveUp (see Client.Err).
+	Delay(attempt int) (time.Duration, bool)
+}
+
+// RetryPolicyFunc is a function implementing RetryPolicy.
+type RetryPolicyFunc func(attempt int) (time.Duration, bool)
+
+func (f RetryPolicyFunc) Delay(attempt int) (time.Duration, bool) { return f(attempt) }
+
+// defaultRetryPolicy starts at 1 second, increasing by 20% after each attempt with a 20% jitter, never giving up.
+// After 30 attempts, the delay is around 3m17s and the time spend retrying is around 16m24s.
+var defaultRetryPolicy = ExponentialBackoff{Initial: time.Second, Multiplier: 1.2, Jitter: 0.2}
+
+// ExponentialBackoff multiplies the delay after each attempt, never giving up (see MaxAttempts).
+type ExponentialBackoff struct {
+	// Initial is the delay after the first attempt. Default 1 second.
+	Initial time.Duration
+	// Max caps the delay. Default 1 hour.
+	Max time.Duration
+	// Multiplier is applied to the delay after each attempt. Default 2.
+	Multiplier float64
+	// Jitter is the fraction of the delay ([0, 1]) randomly removed, so a fleet of clients doesn't reconnect in lockstep.
+	Jitter float64
+}
+
+func (b ExponentialBackoff) Delay(attempt int) (time.Duration, bool) {
+	initial, maxDelay, multiplier := b.Initial, b.Max, b.Multiplier
+	if initial <= 0 {
+		initial = time.Second
+	}
+	if maxDelay <= 0 {
+		maxDelay = time.Hour
+	}
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	delay := min(float64(initial)*math.Pow(multiplier, float64(attempt-1)), float64(maxDelay))
+	if b.Jitter > 0 {
+		delay -= delay * min(b.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay).Round(time.Millisecond), true
+}
+
+// ConstantBackoff waits the same delay after each attempt, never giving up (see MaxAttempts).
+type ConstantBackoff time.Duration
+
+func (b ConstantBackoff) Delay(_ int) (time.Duration, bool) {
+	return time.Duration(b), true
+}
+
+// MaxAttempts gives up reconnecting after the given number of failed attempts, following the policy until then.
+func MaxAttempts(policy RetryPolicy, attempts int) RetryPolicy {
+	return RetryPolicyFunc(func(attempt int) (time.Duration, bool) {
+		if attempt >= attempts {
+			return 0, false
+		}
+		return policy.Delay(attempt)
+	})
+}
@@ -0,0 +1,130 @@
+package tunnel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	for attempt, expected := range []time.Duration{time.Second, 1200 * time.Millisecond, 1440 * time.Millisecond} {
+		delay, retry := defaultRetryPolicy.Delay(attempt + 1)
+		assert.True(t, retry)
+		assert.LessOrEqual(t, delay, expected)
+		assert.GreaterOrEqual(t, delay, expected*8/10)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		backoff  ExponentialBackoff
+		expected []time.Duration
+	}{
+		{
+			name:     "defaults",
+			backoff:  ExponentialBackoff{},
+			expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:     "multiplier",
+			backoff:  ExponentialBackoff{Initial: 100 * time.Millisecond, Multiplier: 1.5},
+			expected: []time.Duration{100 * time.Millisecond, 150 * time.Millisecond, 225 * time.Millisecond},
+		},
+		{
+			name:     "max",
+			backoff:  ExponentialBackoff{Initial: time.Second, Max: 3 * time.Second},
+			expected: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for attempt, expected := range tc.expected {
+				delay, retry := tc.backoff.Delay(attempt + 1)
+				assert.True(t, retry)
+				assert.Equal(t, expected, delay)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoff_Jitter(t *testing.T) {
+	backoff := ExponentialBackoff{Initial: time.Second, Jitter: 0.5}
+
+	delays := make(map[time.Duration]bool)
+	for range 20 {
+		delay, _ := backoff.Delay(1)
+		assert.LessOrEqual(t, delay, time.Second)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		delays[delay] = true
+	}
+	assert.Greater(t, len(delays), 1)
+}
+
+func TestExponentialBackoff_Overflow(t *testing.T) {
+	delay, retry := ExponentialBackoff{}.Delay(1000)
+
+	assert.True(t, retry)
+	assert.Equal(t, time.Hour, delay)
+}
+
+func TestConstantBackoff(t *testing.T) {
+	for attempt := 1; attempt < 5; attempt++ {
+		delay, retry := ConstantBackoff(time.Second).Delay(attempt)
+		assert.True(t, retry)
+		assert.Equal(t, time.Second, delay)
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	policy := MaxAttempts(ConstantBackoff(time.Second), 3)
+
+	for attempt := 1; attempt < 3; attempt++ {
+		delay, retry := policy.Delay(attempt)
+		assert.True(t, retry)
+		assert.Equal(t, time.Second, delay)
+	}
+	_, retry := policy.Delay(3)
+	assert.False(t, retry)
+}
+
+func TestClient_GiveUpReconnecting(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("", WithRetryPolicy(MaxAttempts(ConstantBackoff(time.Millisecond), 2)))
+	require.NoError(t, err)
+	defer cl.Stop()
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("MyTunnel", func(_ string) {})
+	require.NoError(t, err)
+	assert.NoError(t, cl.Err())
+
+	// Lose the connection, the server is no longer reachable
+	tcpClient.connect = func() error { return errors.New("unreachable") }
+	tcpClient.disconnect()
+
+	select {
+	case <-cl.Done():
+	case <-time.After(time.Second):
+		t.Fatal("client should have given up reconnecting")
+	}
+	assert.ErrorIs(t, cl.Err(), ErrReconnectGaveUp)
+	assert.ErrorContains(t, cl.Err(), "after 2 attempts: unreachable")
+	assert.ErrorIs(t, sub.Err(), ErrReconnectGaveUp)
+	assert.ErrorIs(t, cl.CreateBTunnel("MyTunnel"), ErrReconnectGaveUp)
+}
+
+func TestClient_ErrAfterStop(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	cl.Stop()
+
+	<-cl.Done()
+	assert.ErrorIs(t, cl.Err(), ErrClientStopped)
+}
@@ -0,0 +1,250 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/codingLayce/tunnel.go/id"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+const (
+	// ReplyToHeader holds the name of the Tunnel the reply of a request must be published to.
+	ReplyToHeader = "reply-to"
+	// CorrelationIDHeader holds the id matching a reply with its request.
+	CorrelationIDHeader = "correlation-id"
+	// ErrorHeader holds the error returned by the request handler, the reply has no body.
+	ErrorHeader = "error"
+
+	// replyTunnelPrefix prefixes the name of the private Tunnel receiving the replies of the Client's requests.
+	replyTunnelPrefix = "_reply."
+)
+
+// RequestHandler handles a request received by HandleRequests and returns the reply's body.
+// A non nil error is sent back to the requester instead of the reply.
+type RequestHandler func(request *Message) ([]byte, error)
+
+// Request publishes the body to the Tunnel and waits for the reply of the handler listening it (see HandleRequests).
+// The reply is received through a private Tunnel, created the first time the Client makes a request.
+// Returns ErrUnsupportedFeature if the server doesn't support the headers and the open publishing,
+// ctx.Err() if the context is done before the reply is received (see WithRequestTimeout if the context has no deadline),
+// ErrConnectionLost if the connection is lost meanwhile, and an error matching ErrRequestFailed if the handler failed.
+func (c *Client) Request(ctx context.Context, tunnelName string, body []byte) (*Message, error) {
+	err := c.checkRPCSupported()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+
+	replyTunnel, err := c.replyTunnel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reply tunnel: %w", err)
+	}
+
+	// The reply channel is stored before publishing so the reply cannot be missed.
+	correlationID := id.New()
+	replyCh := make(chan *Message, 1)
+	if !c.replies.PutIfAbsent(correlationID, replyCh) {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateTransactionID, correlationID)
+	}
+	defer c.replies.Delete(correlationID)
+
+	internal, err := c.publish(ctx, &Message{TunnelName: tunnelName, Body: body, Headers: map[string]string{
+		ReplyToHeader:       replyTunnel,
+		CorrelationIDHeader: correlationID,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	// The reply is lost with the connection the request has been published on, the handler may have processed
+	// the request anyway.
+	select {
+	case reply := <-replyCh:
+		if reason, failed := reply.Headers[ErrorHeader]; failed {
+			return nil, fmt.Errorf("%w: %s", ErrRequestFailed, reason)
+		}
+		return reply, nil
+	case <-internal.Done():
+		return nil, ErrConnectionLost
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, c.Err()
+	}
+}
+
+// HandleRequests listens the Tunnel and replies to the requests (see Request) with the handler's result.
+// Each request is handled in its own routine, once it has been acknowledged to the server: the requests are handled
+// at most once, a request whose handling is interrupted is not delivered again (the requester times out).
+// The messages without ReplyToHeader are discarded.
+// Returns ErrUnsupportedFeature if the server doesn't support the headers and the open publishing.
+func (c *Client) HandleRequests(tunnelName string, handler RequestHandler) (*Subscription, error) {
+	return c.HandleRequestsContext(context.Background(), tunnelName, handler)
+}
+
+// HandleRequestsContext is HandleRequests, returning ctx.Err() if the context is done before the server's response.
+// The context only applies to the listen request, not to the returned Subscription.
+func (c *Client) HandleRequestsContext(ctx context.Context, tunnelName string, handler RequestHandler) (*Subscription, error) {
+	err := c.checkRPCSupported()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ListenTunnelMessagesContext(ctx, tunnelName, func(request *Message) {
+		replyTo, ok := request.Headers[ReplyToHeader]
+		if !ok {
+			c.Logger.Warn("Received request without reply tunnel. Discarding it.", "tunnel_name", tunnelName)
+			return
+		}
+
+		// Not blocking the Subscription: replying waits for the server's ack.
+		started := c.spawn(func() {
+			c.handleRequest(request, replyTo, handler)
+		})
+		if !started {
+			c.Logger.Debug("Client stopped. Request not handled", "tunnel_name", tunnelName)
+		}
+	})
+}
+
+func (c *Client) checkRPCSupported() error {
+	// The requests and the replies carry headers and are published to Tunnels the publisher isn't listening to.
+	capabilities := c.Capabilities()
+	for _, capability := range []command.Capability{command.HeadersCapability, command.OpenPublishCapability} {
+		if !capabilities.Has(capability) {
+			err := fmt.Errorf("%w: %s", ErrUnsupportedFeature, capability)
+			c.Logger.Error("Cannot use the requests", "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) handleRequest(request *Message, replyTo string, handler RequestHandler) {
+	headers := map[string]string{CorrelationIDHeader: request.Headers[CorrelationIDHeader]}
+	body, err := handler(request)
+	if err != nil {
+		headers[ErrorHeader] = err.Error()
+		body = nil
+	}
+
+	err = c.PublishWithHeaders(replyTo, body, headers)
+	if err != nil {
+		c.Logger.Warn("Cannot reply to the request", "tunnel_name", request.TunnelName, "reply_tunnel", replyTo, "error", err)
+	}
+}
+
+func (c *Client) replyTunnel(ctx context.Context) (string, error) {
+	// Lazily creates and listens the private Tunnel receiving the replies.
+	// The creation is shared by the concurrent requests, each one waiting for it with its own context.
+	// A failed creation is attempted again by the next request.
+	c.replyMtx.Lock()
+	future := c.replyFuture
+	if future == nil || future.failed() {
+		future = &replyTunnelFuture{done: make(chan struct{})}
+		if !c.spawn(func() { c.createReplyTunnel(future) }) {
+			c.replyMtx.Unlock()
+			return "", c.Err()
+		}
+		c.replyFuture = future
+	}
+	c.replyMtx.Unlock()
+
+	select {
+	case <-future.done:
+		return future.name, future.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-c.ctx.Done():
+		return "", c.Err()
+	}
+}
+
+func (c *Client) createReplyTunnel(future *replyTunnelFuture) {
+	defer close(future.done)
+
+	// Not bound to the context of the request triggering it, the other requests may wait for it.
+	ctx, cancel := context.WithTimeout(c.ctx, c.requestTimeout)
+	defer cancel()
+
+	name := replyTunnelPrefix + id.New()
+	err := c.CreateBTunnelContext(ctx, name)
+	if err != nil {
+		future.err = err
+		return
+	}
+	_, err = c.ListenTunnelMessagesContext(ctx, name, c.replyReceived)
+	if err != nil {
+		future.err = errors.Join(err, c.DeleteTunnelContext(ctx, name))
+		return
+	}
+	future.name = name
+}
+
+func (c *Client) replyReceived(reply *Message) {
+	correlationID := reply.Headers[CorrelationIDHeader]
+	replyCh, ok := c.replies.Get(correlationID)
+	if !ok {
+		c.Logger.Warn("Received unexpected reply. Discarding it.", "correlation_id", correlationID)
+		return
+	}
+	select { // The reply channel is buffered, only the first reply is kept.
+	case replyCh <- reply:
+	default:
+	}
+}
+
+func (c *Client) deleteReplyTunnel() {
+	// Invoked when stopping, the private Tunnel is useless once the Client is stopped.
+	// Best effort: not attempted while disconnected or while it's being created, and not waiting for the reconnection
+	// nor the ack timeout (see WithStopTimeout).
+	c.replyMtx.Lock()
+	future := c.replyFuture
+	c.replyFuture = nil
+	c.replyMtx.Unlock()
+
+	if future == nil || !future.created() {
+		return
+	}
+	if c.State() == StateConnected {
+		ctx, cancel := context.WithTimeout(context.Background(), c.stopTimeout)
+		defer cancel()
+		err := c.DeleteTunnelContext(ctx, future.name)
+		if err != nil {
+			c.Logger.Warn("Cannot delete the reply tunnel", "tunnel_name", future.name, "error", err)
+		}
+	}
+}
+
+// replyTunnelFuture is the creation of the private Tunnel receiving the replies.
+// name and err are set once done is closed.
+type replyTunnelFuture struct {
+	done chan struct{}
+	name string
+	err  error
+}
+
+func (f *replyTunnelFuture) created() bool {
+	select {
+	case <-f.done:
+		return f.err == nil
+	default:
+		return false
+	}
+}
+
+func (f *replyTunnelFuture) failed() bool {
+	select {
+	case <-f.done:
+		return f.err != nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,333 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// serveRequests acks every command received by the server, and replies to the requests published to "service"
+// with the response built by reply (no reply when nil).
+func serveRequests(tcpClient *TestTCPClient, reply func(request *command.PublishMessage) *command.ReceiveMessage) {
+	go func() {
+		for cmd := range tcpClient.commandsChan() {
+			go tcpClient.callOnPayload(pdu.Marshal(ack(cmd)))
+
+			request, ok := cmd.(*command.PublishMessage)
+			if !ok || request.TunnelName != "service" {
+				continue
+			}
+			if response := reply(request); response != nil {
+				go tcpClient.callOnPayload(pdu.Marshal(response))
+			}
+		}
+	}()
+}
+
+// setReplyTunnel makes the Client use the given reply tunnel, as if it had created it.
+func setReplyTunnel(cl *Client, name string) {
+	future := &replyTunnelFuture{done: make(chan struct{}), name: name}
+	close(future.done)
+	cl.replyMtx.Lock()
+	defer cl.replyMtx.Unlock()
+	cl.replyFuture = future
+}
+
+func TestClient_Request(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.OpenPublishCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	serveRequests(tcpClient, func(request *command.PublishMessage) *command.ReceiveMessage {
+		assert.Equal(t, []byte("ping"), request.Message)
+		assert.True(t, strings.HasPrefix(request.Headers[ReplyToHeader], replyTunnelPrefix))
+		reply := command.NewReceiveMessage(request.Headers[ReplyToHeader], []byte("pong"))
+		reply.Headers = command.Headers{CorrelationIDHeader: request.Headers[CorrelationIDHeader]}
+		return reply
+	})
+
+	for range 2 { // The reply tunnel is reused
+		reply, err := cl.Request(context.Background(), "service", []byte("ping"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("pong"), reply.Body)
+	}
+}
+
+func TestClient_Request_HandlerError(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.OpenPublishCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	serveRequests(tcpClient, func(request *command.PublishMessage) *command.ReceiveMessage {
+		reply := command.NewReceiveMessage(request.Headers[ReplyToHeader], nil)
+		reply.Headers = command.Headers{
+			CorrelationIDHeader: request.Headers[CorrelationIDHeader],
+			ErrorHeader:         "boom",
+		}
+		return reply
+	})
+
+	_, err = cl.Request(context.Background(), "service", []byte("ping"))
+	assert.ErrorIs(t, err, ErrRequestFailed)
+	assert.EqualError(t, err, "request failed: boom")
+}
+
+func TestClient_Request_Timeout(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.OpenPublishCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	serveRequests(tcpClient, func(_ *command.PublishMessage) *command.ReceiveMessage {
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = cl.Request(ctx, "service", []byte("ping"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_Request_ReplyTunnelError(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.OpenPublishCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		_, ok := cmd.(*command.CreateTunnel)
+		assert.True(t, ok, "Server should have received a CreateTunnel command")
+		return command.NewNackWithTransactionID(cmd.TransactionID())
+	})
+
+	_, err = cl.Request(context.Background(), "service", []byte("ping"))
+	assert.EqualError(t, err, "reply tunnel: server nack")
+}
+
+func TestClient_Request_ReplyTunnelPending(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.OpenPublishCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("", WithAckTimeout(time.Second))
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	// The server doesn't respond to the creation of the reply tunnel yet.
+	first := make(chan error, 1)
+	go func() {
+		_, err := cl.Request(context.Background(), "service", []byte("ping"))
+		first <- err
+	}()
+	createTunnel := receiveCommand(t, tcpClient)
+	_, ok := createTunnel.(*command.CreateTunnel)
+	require.True(t, ok, "Server should have received a CreateTunnel command")
+
+	// Another request waits for the same creation, until its own context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = cl.Request(ctx, "service", []byte("ping"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// The creation fails, the next request attempts it again.
+	tcpClient.callOnPayload(pdu.Marshal(command.NewNackWithTransactionID(createTunnel.TransactionID())))
+	assert.EqualError(t, <-first, "reply tunnel: server nack")
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		_, ok := cmd.(*command.CreateTunnel)
+		assert.True(t, ok, "Server should have received a CreateTunnel command")
+		return command.NewNackWithTransactionID(cmd.TransactionID())
+	})
+	_, err = cl.Request(context.Background(), "service", []byte("ping"))
+	assert.EqualError(t, err, "reply tunnel: server nack")
+}
+
+func TestClient_Request_UnsupportedFeature(t *testing.T) {
+	for name, capabilities := range map[string][]command.Capability{
+		"headers":      {command.OpenPublishCapability},
+		"open_publish": {command.HeadersCapability},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tcpClient := newTestTCPClientWithCapabilities(capabilities...)
+			mockNewTCPClient(t, tcpClient)
+
+			cl, err := Connect("")
+			require.NoError(t, err)
+			defer cl.Stop()
+
+			_, err = cl.Request(context.Background(), "service", []byte("ping"))
+			assert.EqualError(t, err, "feature not supported by the server: "+name)
+			assert.ErrorIs(t, err, ErrUnsupportedFeature)
+
+			_, err = cl.HandleRequests("service", func(*Message) ([]byte, error) { return nil, nil })
+			assert.EqualError(t, err, "feature not supported by the server: "+name)
+		})
+	}
+}
+
+func TestClient_HandleRequests(t *testing.T) {
+	for name, tc := range map[string]struct {
+		handler         RequestHandler
+		expectedBody    []byte
+		expectedHeaders command.Headers
+	}{
+		"Reply": {
+			handler: func(request *Message) ([]byte, error) {
+				return append([]byte("re: "), request.Body...), nil
+			},
+			expectedBody:    []byte("re: ping"),
+			expectedHeaders: command.Headers{CorrelationIDHeader: "42"},
+		},
+		"Error": {
+			handler: func(_ *Message) ([]byte, error) {
+				return []byte("ignored"), errors.New("boom")
+			},
+			expectedBody:    []byte{},
+			expectedHeaders: command.Headers{CorrelationIDHeader: "42", ErrorHeader: "boom"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.OpenPublishCapability)
+			mockNewTCPClient(t, tcpClient)
+
+			cl, err := Connect("")
+			require.NoError(t, err)
+			defer cl.Stop()
+
+			replyNext(t, tcpClient, ack)
+			_, err = cl.HandleRequests("service", tc.handler)
+			require.NoError(t, err)
+
+			request := command.NewReceiveMessage("service", []byte("ping"))
+			request.Headers = command.Headers{ReplyToHeader: "_reply.abcd1234", CorrelationIDHeader: "42"}
+			go tcpClient.callOnPayload(pdu.Marshal(request))
+
+			// The request's ack and the reply are sent concurrently, wait for both before stopping the client
+			var reply *command.PublishMessage
+			acked := false
+			for reply == nil || !acked {
+				select {
+				case cmd := <-tcpClient.commandsChan():
+					switch castedCMD := cmd.(type) {
+					case *command.Ack:
+						assert.Equal(t, request.TransactionID(), cmd.TransactionID())
+						acked = true
+					case *command.PublishMessage:
+						reply = castedCMD
+						go tcpClient.callOnPayload(pdu.Marshal(ack(cmd)))
+					default:
+						assert.FailNow(t, "Unexpected command", cmd.Info())
+					}
+				case <-time.After(100 * time.Millisecond):
+					assert.FailNow(t, "Server should have received the reply")
+				}
+			}
+
+			assert.Equal(t, "_reply.abcd1234", reply.TunnelName)
+			assert.Equal(t, tc.expectedBody, reply.Message)
+			assert.Equal(t, tc.expectedHeaders, reply.Headers)
+		})
+	}
+}
+
+func TestClient_HandleRequests_NoReplyTunnel(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.OpenPublishCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	_, err = cl.HandleRequests("service", func(_ *Message) ([]byte, error) {
+		assert.Fail(t, "Handler should not have been invoked")
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	go tcpClient.callOnPayload(pdu.Marshal(command.NewReceiveMessage("service", []byte("ping"))))
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		_, isAck := cmd.(*command.Ack)
+		assert.True(t, isAck, "Message should have been ack")
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A ack should have been received server side")
+	}
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		assert.Fail(t, "No reply should have been published", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_Stop_ReplyTunnelDeletionTimeout(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.HeadersCapability, command.OpenPublishCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("", WithStopTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	setReplyTunnel(cl, replyTunnelPrefix+"abcd1234")
+
+	stopped := make(chan struct{})
+	go func() {
+		cl.Stop()
+		close(stopped)
+	}()
+
+	// The server never acknowledges the deletion.
+	deleteTunnel, ok := receiveCommand(t, tcpClient).(*command.DeleteTunnel)
+	require.True(t, ok, "Server should have received a DeleteTunnel command")
+	assert.Equal(t, replyTunnelPrefix+"abcd1234", deleteTunnel.Name)
+	select {
+	case <-stopped:
+	case <-time.After(500 * time.Millisecond):
+		assert.FailNow(t, "Stop should not wait for the ack timeout")
+	}
+}
+
+func TestClient_Stop_ReplyTunnelWhileDisconnected(t *testing.T) {
+	tcpClient, reconnect := newReconnectingTCPClient(t)
+
+	cl, err := Connect("", WithWaitForReconnect(true), WithRetryPolicy(ConstantBackoff(time.Millisecond)))
+	require.NoError(t, err)
+	setReplyTunnel(cl, replyTunnelPrefix+"abcd1234")
+
+	tcpClient.disconnect()
+	assert.Eventually(t, func() bool { return cl.State() == StateReconnecting }, time.Second, 5*time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		cl.Stop()
+		close(stopped)
+	}()
+	select {
+	case reconnect <- errors.New("unreachable"):
+	case <-time.After(500 * time.Millisecond):
+		assert.FailNow(t, "Stop should not wait for the reconnection")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(500 * time.Millisecond):
+		assert.FailNow(t, "Stop should not wait for the reconnection")
+	}
+}
@@ -0,0 +1,137 @@
+package tunnel
+
+import "sync"
+
+// State is the connection state of the Client.
+type State int
+
+const (
+	// StateConnecting is the state while Connect establishes the first connection.
+	StateConnecting State = iota
+	// StateConnected is the state while the Client is connected to the server (handshake done).
+	StateConnected
+	// StateDisconnected is the state right after the connection with the server is lost.
+	StateDisconnected
+	// StateReconnecting is the state while the Client tries to reconnect, following the RetryPolicy.
+	StateReconnecting
+	// StateStopped is the final state, after Stop or when the Client gave up connecting (see Client.Err).
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// StateChange is a transition of the Client's connection State.
+type StateChange struct {
+	From State
+	To   State
+	// Attempt is the number of the reconnection attempt (starting at 1) when To is StateReconnecting, 0 otherwise.
+	// Each attempt is a transition, From being StateReconnecting after the first one.
+	Attempt int
+	// Err is the reason of the transition when known: the error of the previous attempt when To is StateReconnecting,
+	// the stop reason when To is StateStopped (see Client.Err).
+	Err error
+}
+
+// stateMachine holds the State of the Client and notifies its observers of the transitions.
+type stateMachine struct {
+	// transitionMtx orders the transitions and their notifications, mtx guards the fields.
+	transitionMtx sync.Mutex
+	mtx           sync.Mutex
+
+	state     State
+	observers map[int]func(StateChange)
+	nextID    int
+	// hook is Hooks.OnStateChange, notified first.
+	hook func(StateChange)
+	// connectedCh is closed while the state is StateConnected, lazily created (see connectedSignal).
+	connectedCh chan struct{}
+}
+
+// State returns the current connection state of the Client.
+func (c *Client) State() State {
+	c.states.mtx.Lock()
+	defer c.states.mtx.Unlock()
+	return c.states.state
+}
+
+// OnStateChange registers the observer of the connection state transitions, returns the function unregistering it.
+// The observer is invoked by the Client's internal routines, so it must not block. It may call State or unregister itself.
+// The transitions before the registration are not replayed, use State to get the current one.
+func (c *Client) OnStateChange(observer func(StateChange)) (unregister func()) {
+	c.states.mtx.Lock()
+	defer c.states.mtx.Unlock()
+	if c.states.observers == nil {
+		c.states.observers = make(map[int]func(StateChange))
+	}
+	id := c.states.nextID
+	c.states.nextID++
+	c.states.observers[id] = observer
+
+	return func() {
+		c.states.mtx.Lock()
+		defer c.states.mtx.Unlock()
+		delete(c.states.observers, id)
+	}
+}
+
+func (c *Client) connectedSignal() <-chan struct{} {
+	// Returns a channel closed once the state is StateConnected.
+	c.states.mtx.Lock()
+	defer c.states.mtx.Unlock()
+	if c.states.connectedCh == nil {
+		c.states.connectedCh = make(chan struct{})
+		if c.states.state == StateConnected {
+			close(c.states.connectedCh)
+		}
+	}
+	return c.states.connectedCh
+}
+
+func (c *Client) setState(to State, attempt int, err error) {
+	// Transitions to the State and notifies the observers. The Stopped state is final.
+	c.states.transitionMtx.Lock()
+	defer c.states.transitionMtx.Unlock()
+
+	c.states.mtx.Lock()
+	from := c.states.state
+	if from == StateStopped {
+		c.states.mtx.Unlock()
+		return
+	}
+	c.states.state = to
+	switch {
+	case to == StateConnected && c.states.connectedCh != nil:
+		close(c.states.connectedCh)
+	case from == StateConnected:
+		c.states.connectedCh = nil
+	}
+	observers := make([]func(StateChange), 0, len(c.states.observers)+1)
+	if c.states.hook != nil {
+		observers = append(observers, c.states.hook)
+	}
+	for _, observer := range c.states.observers {
+		observers = append(observers, observer)
+	}
+	c.states.mtx.Unlock()
+
+	c.Logger.Debug("Connection state changed", "from", from, "to", to, "attempt", attempt, "error", err)
+	change := StateChange{From: from, To: to, Attempt: attempt, Err: err}
+	for _, observer := range observers {
+		observer(change)
+	}
+}
@@ -0,0 +1,141 @@
+package tunnel
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateRecorder records the connection state transitions.
+type stateRecorder struct {
+	changes []StateChange
+	mtx     sync.Mutex
+}
+
+func (r *stateRecorder) record(change StateChange) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.changes = append(r.changes, change)
+}
+
+func (r *stateRecorder) waitFor(t *testing.T, count int) []StateChange {
+	assert.Eventually(t, func() bool {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		return len(r.changes) >= count
+	}, time.Second, 5*time.Millisecond)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]StateChange(nil), r.changes...)
+}
+
+func TestClient_State(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	failures := atomic.Int32{}
+	unreachable := errors.New("unreachable")
+	tcpClient.connect = func() error {
+		if failures.Load() > 0 {
+			failures.Add(-1)
+			return unreachable
+		}
+		return nil
+	}
+	mockNewTCPClient(t, tcpClient)
+
+	hook := &stateRecorder{}
+	cl, err := Connect("",
+		WithRetryPolicy(ConstantBackoff(time.Millisecond)),
+		WithHooks(Hooks{OnStateChange: hook.record}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, StateConnected, cl.State())
+	observer := &stateRecorder{}
+	cl.OnStateChange(observer.record)
+
+	// Lose the connection, the 2 first reconnection attempts fail
+	failures.Store(2)
+	tcpClient.disconnect()
+
+	expected := []StateChange{
+		{From: StateConnected, To: StateDisconnected},
+		{From: StateDisconnected, To: StateReconnecting, Attempt: 1},
+		{From: StateReconnecting, To: StateReconnecting, Attempt: 2, Err: unreachable},
+		{From: StateReconnecting, To: StateReconnecting, Attempt: 3, Err: unreachable},
+		{From: StateReconnecting, To: StateConnected},
+	}
+	assert.Equal(t, expected, observer.waitFor(t, len(expected)))
+	assert.Equal(t, StateConnected, cl.State())
+
+	cl.Stop()
+	assert.Equal(t, StateStopped, cl.State())
+	stopped := StateChange{From: StateConnected, To: StateStopped, Err: ErrClientStopped}
+	assert.Equal(t, append(expected, stopped), observer.waitFor(t, len(expected)+1))
+	connected := StateChange{From: StateConnecting, To: StateConnected}
+	assert.Equal(t, append([]StateChange{connected}, append(expected, stopped)...), hook.waitFor(t, len(expected)+2))
+}
+
+func TestClient_State_GiveUp(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("", WithRetryPolicy(MaxAttempts(ConstantBackoff(time.Millisecond), 1)))
+	require.NoError(t, err)
+	defer cl.Stop()
+	observer := &stateRecorder{}
+	cl.OnStateChange(observer.record)
+
+	tcpClient.connect = func() error { return errors.New("unreachable") }
+	tcpClient.disconnect()
+
+	changes := observer.waitFor(t, 3)
+	require.Len(t, changes, 3)
+	assert.Equal(t, StateStopped, changes[2].To)
+	assert.ErrorIs(t, changes[2].Err, ErrReconnectGaveUp)
+	assert.Equal(t, StateStopped, cl.State())
+}
+
+func TestClient_State_ConnectFailure(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	tcpClient.connect = func() error { return errors.New("unreachable") }
+	mockNewTCPClient(t, tcpClient)
+
+	hook := &stateRecorder{}
+	_, err := Connect("", WithHooks(Hooks{OnStateChange: hook.record}))
+	require.Error(t, err)
+
+	changes := hook.waitFor(t, 1)
+	require.Len(t, changes, 1)
+	assert.Equal(t, StateConnecting, changes[0].From)
+	assert.Equal(t, StateStopped, changes[0].To)
+	assert.EqualError(t, changes[0].Err, "unreachable")
+}
+
+func TestClient_OnStateChange_Unregister(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	observer := &stateRecorder{}
+	unregister := cl.OnStateChange(observer.record)
+	unregister()
+
+	cl.Stop()
+
+	assert.Empty(t, observer.changes)
+}
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "connecting", StateConnecting.String())
+	assert.Equal(t, "connected", StateConnected.String())
+	assert.Equal(t, "disconnected", StateDisconnected.String())
+	assert.Equal(t, "reconnecting", StateReconnecting.String())
+	assert.Equal(t, "stopped", StateStopped.String())
+	assert.Equal(t, "unknown", State(42).String())
+}
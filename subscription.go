@@ -0,0 +1,153 @@
+package tunnel
+
+import (
+	"context"
+	"sync"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// MaxQueuedMessages is the maximum number of messages a Subscription queues while its handler is running.
+// The messages received while the queue is full are requeued (see ErrQueueFull), the server delivering them again later.
+const MaxQueuedMessages = 1000
+
+// Subscription is the listening of a Tunnel, returned by the ListenTunnel methods.
+// It ends when Unsubscribe is called, when the Tunnel is deleted or when the Client is stopped.
+type Subscription struct {
+	client     *Client
+	tunnelName string
+
+	// queue holds the Tunnel's messages until the listening goroutine handles them, so a slow handler
+	// never blocks the reading of the connection. Guarded by queueMtx, queued is signaled by each push.
+	queue    []*delivery
+	queueMtx sync.Mutex
+	queued   chan struct{}
+
+	ctx    context.Context
+	stopFn context.CancelCauseFunc
+}
+
+func newSubscription(client *Client, tunnelName string) *Subscription {
+	sub := &Subscription{
+		client:     client,
+		tunnelName: tunnelName,
+		queued:     make(chan struct{}, 1),
+	}
+	sub.ctx, sub.stopFn = context.WithCancelCause(client.ctx)
+	return sub
+}
+
+// TunnelName returns the name of the listened Tunnel.
+func (s *Subscription) TunnelName() string {
+	return s.tunnelName
+}
+
+// Done is closed when the Subscription has ended.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Err returns the reason why the Subscription has ended, nil while it is active:
+//   - ErrUnsubscribed after Unsubscribe
+//   - ErrTunnelDeleted when the Tunnel has been deleted
+//   - ErrClientStopped when the Client has been stopped
+//   - ErrReconnectGaveUp when the Client gave up reconnecting
+func (s *Subscription) Err() error {
+	if s.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(s.ctx) // The Client's stop cause is inherited.
+}
+
+// Unsubscribe asks the server to stop sending the Tunnel's messages and ends the Subscription.
+// The Subscription is ended even if the server doesn't acknowledge the request.
+// Calling it on an ended Subscription does nothing.
+func (s *Subscription) Unsubscribe() error {
+	return s.UnsubscribeContext(context.Background())
+}
+
+// UnsubscribeContext is Unsubscribe, returning ctx.Err() if the context is done before the server's response.
+// The Subscription is ended anyway.
+func (s *Subscription) UnsubscribeContext(ctx context.Context) error {
+	if s.ctx.Err() != nil {
+		return nil
+	}
+	defer s.end(ErrUnsubscribed)
+
+	err := s.client.sendCommandAndWaitAck(ctx, command.NewUnlistenTunnelWithTransactionID(s.client.newTransactionID(), s.tunnelName))
+	if err != nil {
+		s.client.Logger.Error("Cannot unlisten Tunnel", "tunnel_name", s.tunnelName, "error", err)
+		return err
+	}
+
+	s.client.Logger.Info("Stopped listening to Tunnel", "tunnel_name", s.tunnelName)
+	return nil
+}
+
+func (s *Subscription) end(cause error) {
+	s.stopFn(cause)
+	s.client.listeners.DeleteIf(s.tunnelName, func(sub *Subscription) bool {
+		return sub == s
+	})
+}
+
+func (s *Subscription) push(msg *delivery) bool {
+	// Queues the message for the listening goroutine, returns false if the Subscription has ended.
+	// A message received while the queue is full is requeued, so the server slows down to the handler's pace.
+	s.queueMtx.Lock()
+	if s.ctx.Err() != nil {
+		s.queueMtx.Unlock()
+		return false
+	}
+	if len(s.queue) >= MaxQueuedMessages {
+		s.queueMtx.Unlock()
+		s.client.Logger.Warn("Subscription queue full. Requeuing the message.", "tunnel_name", s.tunnelName)
+		msg.settle(ErrQueueFull)
+		return true
+	}
+	s.queue = append(s.queue, msg)
+	s.queueMtx.Unlock()
+
+	select {
+	case s.queued <- struct{}{}:
+	default: // Already signaled
+	}
+	return true
+}
+
+func (s *Subscription) pop() []*delivery {
+	s.queueMtx.Lock()
+	defer s.queueMtx.Unlock()
+	queue := s.queue
+	s.queue = nil
+	return queue
+}
+
+func (s *Subscription) listen(handler MessageHandler) {
+	defer s.client.wg.Done()
+
+	for {
+		select {
+		case <-s.queued:
+			for _, msg := range s.pop() {
+				if s.ctx.Err() != nil {
+					msg.drop()
+					continue
+				}
+				s.client.Logger.Debug("Received message", "tunnel_name", s.tunnelName, "message_size", len(msg.message.Body))
+				err := handler(msg.message)
+				if err != nil {
+					s.client.Logger.Warn("Cannot process message", "tunnel_name", s.tunnelName, "error", err)
+				}
+				msg.settle(err)
+			}
+		case <-s.ctx.Done():
+			s.client.Logger.Debug("Stop listening Tunnel", "tunnel_name", s.tunnelName)
+			// The messages queued before the end are not handled. No message can be pushed anymore.
+			for _, msg := range s.pop() {
+				msg.drop()
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package tunnel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+// replyNext replies to the next command received by the server with the given response builder.
+func TestSubscription_Unsubscribe(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("Bidule", func(_ string) {
+		assert.Fail(t, "No message should have been received")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Bidule", sub.TunnelName())
+
+	unlistened := make(chan *command.UnlistenTunnel, 1)
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		unlisten, ok := cmd.(*command.UnlistenTunnel)
+		if assert.True(t, ok, "Server should have received an UnlistenTunnel command") {
+			unlistened <- unlisten
+		}
+		return ack(cmd)
+	})
+	assert.NoError(t, sub.Err())
+	require.NoError(t, sub.Unsubscribe())
+	assert.Equal(t, "Bidule", (<-unlistened).Name)
+
+	select {
+	case <-sub.Done():
+	default:
+		assert.Fail(t, "Subscription should have ended")
+	}
+	assert.ErrorIs(t, sub.Err(), ErrUnsubscribed)
+	assert.False(t, cl.listeners.Has("Bidule"))
+
+	// Messages received after unsubscribing are ignored.
+	tcpClient.callOnPayload(pdu.Marshal(command.NewReceiveMessage("Bidule", []byte("This is a message"))))
+
+	// Already ended: nothing is sent.
+	require.NoError(t, sub.Unsubscribe())
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		assert.Fail(t, "No command should have been sent", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscription_Unsubscribe_NackError(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("Bidule", func(_ string) {})
+	require.NoError(t, err)
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		nack := command.NewNackWithTransactionID(cmd.TransactionID())
+		nack.Code = command.NackTunnelNotFound
+		return nack
+	})
+	err = sub.Unsubscribe()
+	assert.True(t, errors.Is(err, ErrTunnelNotFound))
+
+	select {
+	case <-sub.Done():
+	default:
+		assert.Fail(t, "Subscription should have ended")
+	}
+	assert.False(t, cl.listeners.Has("Bidule"))
+}
+
+func TestSubscription_ClientStopped(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("Bidule", func(_ string) {})
+	require.NoError(t, err)
+
+	cl.Stop()
+
+	select {
+	case <-sub.Done():
+	default:
+		assert.Fail(t, "Subscription should have ended")
+	}
+	assert.ErrorIs(t, sub.Err(), ErrClientStopped)
+}
+
+func TestSubscription_TunnelDeleted(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("Bidule", func(_ string) {})
+	require.NoError(t, err)
+
+	deletion := command.NewDeleteTunnel("Bidule")
+	go tcpClient.callOnPayload(pdu.Marshal(deletion))
+
+	select {
+	case <-sub.Done():
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "Subscription should have ended")
+	}
+	assert.ErrorIs(t, sub.Err(), ErrTunnelDeleted)
+	assert.False(t, cl.listeners.Has("Bidule"))
+
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		_, isAck := cmd.(*command.Ack)
+		assert.True(t, isAck, "Deletion should have been ack")
+		assert.Equal(t, deletion.TransactionID(), cmd.TransactionID())
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A ack should have been received server side")
+	}
+
+	// Already ended: nothing is sent.
+	require.NoError(t, sub.Unsubscribe())
+}
+
+func TestClient_ListenTunnel_AlreadyListening(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+	sub, err := cl.ListenTunnel("Bidule", func(_ string) {})
+	require.NoError(t, err)
+
+	_, err = cl.ListenTunnel("Bidule", func(_ string) {})
+	assert.ErrorIs(t, err, ErrAlreadyListening)
+	assert.EqualError(t, err, "already listening: Bidule")
+
+	replyNext(t, tcpClient, ack)
+	require.NoError(t, sub.Unsubscribe())
+
+	// Listening again is allowed once unsubscribed.
+	replyNext(t, tcpClient, ack)
+	_, err = cl.ListenTunnel("Bidule", func(_ string) {})
+	require.NoError(t, err)
+}
+
+func TestClient_ListenTunnel_NackError_Unregistered(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		return command.NewNackWithTransactionID(cmd.TransactionID())
+	})
+	_, err = cl.ListenTunnel("Bidule", func(_ string) {})
+	assert.EqualError(t, err, "server nack")
+	assert.False(t, cl.listeners.Has("Bidule"))
+}
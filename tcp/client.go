@@ -1,16 +1,38 @@
 package tcp
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
+	"time"
+
+	"github.com/codingLayce/tunnel.go/pdu"
 )
 
+// Dialer opens the connections to the server (i.e. *net.Dialer, a proxy or a TLS dialer).
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
 type ClientOption struct {
 	Addr string
 
+	// Dialer opens the connection. Default a *net.Dialer.
+	Dialer Dialer
+
+	// ReadTimeout is the allowed idle duration before disconnecting from the server.
+	ReadTimeout time.Duration
+
 	// OnPayload is invoked when the server has sent a payload.
 	OnPayload func(payload []byte)
+
+	// Framing used to split the payloads sent by the server.
+	Framing pdu.Framing
+
+	// MaxFrameSize is the maximum payload size (in bytes) the server can send before being disconnected.
+	// 0 means pdu.DefaultMaxFrameSize.
+	MaxFrameSize int
 }
 
 type Client struct {
@@ -22,7 +44,14 @@ type Client struct {
 	stopped chan struct{}
 }
 
+func (opts *ClientOption) defaults() {
+	if opts.Dialer == nil {
+		opts.Dialer = &net.Dialer{}
+	}
+}
+
 func NewClient(opts *ClientOption) *Client {
+	opts.defaults()
 	return &Client{
 		opts:    opts,
 		stopped: make(chan struct{}),
@@ -30,7 +59,7 @@ func NewClient(opts *ClientOption) *Client {
 }
 
 func (c *Client) Connect() error {
-	conn, err := net.Dial("tcp", c.opts.Addr)
+	conn, err := c.opts.Dialer.DialContext(context.Background(), "tcp", c.opts.Addr)
 	if err != nil {
 		return fmt.Errorf("dial: %w", err)
 	}
@@ -48,6 +77,9 @@ func (c *Client) Connect() error {
 				close(c.stopped)
 			}
 		},
+		ReadTimeout:  c.opts.ReadTimeout,
+		Framing:      c.opts.Framing,
+		MaxFrameSize: c.opts.MaxFrameSize,
 	})
 
 	c.wg.Add(1)
@@ -74,6 +106,12 @@ func (c *Client) Done() <-chan struct{} {
 	return c.stopped
 }
 
+// SetFraming changes the framing used to read the next payloads sent by the server.
+// To switch without misreading a payload, it must be invoked from the OnPayload callback.
+func (c *Client) SetFraming(framing pdu.Framing) {
+	c.conn.SetFraming(framing)
+}
+
 func (c *Client) Send(payload []byte) error {
 	return c.conn.Send(payload)
 }
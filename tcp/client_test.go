@@ -1,12 +1,18 @@
 package tcp
 
 import (
+	"context"
 	"log/slog"
+	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
 )
 
 func TestClient(t *testing.T) {
@@ -64,3 +70,80 @@ func TestClient(t *testing.T) {
 		assert.FailNow(t, "Client should have stopped")
 	}
 }
+
+func TestClient_SetFraming(t *testing.T) {
+	serverSideClient := make(chan *Connection)
+	server := NewServer(&ServerOption{
+		Addr: ":0",
+		OnConnectionReceived: func(conn *Connection) {
+			serverSideClient <- conn
+		},
+	})
+
+	err := server.Start()
+	require.NoError(t, err)
+	defer server.Stop()
+
+	var cl *Client
+	payloadReceived := make(chan []byte)
+	cl = NewClient(&ClientOption{
+		Addr: server.Addr(),
+		OnPayload: func(payload []byte) {
+			// Switching from the callback so the next payload is read with the new framing
+			cl.SetFraming(pdu.LengthPrefixedFraming)
+			payloadReceived <- payload
+		},
+	})
+
+	err = cl.Connect()
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	first := pdu.Marshal(command.NewAckWithTransactionID("abcd1234"))
+	second := pdu.LengthPrefixedFraming.Marshal(command.NewReceiveMessageWithTransactionID("abcd1234", "Bidule", []byte("Multi\nline")))
+
+	select {
+	case conn := <-serverSideClient:
+		go func() {
+			err := conn.Send(append(append([]byte{}, first...), second...))
+			require.NoError(t, err)
+		}()
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "A connection should have been initiated on the server")
+	}
+
+	for _, expected := range [][]byte{first, second} {
+		select {
+		case payload := <-payloadReceived:
+			assert.Equal(t, expected, payload)
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "A payload should have been received to the client")
+		}
+	}
+}
+
+type countingDialer struct {
+	net.Dialer
+	dials atomic.Int32
+}
+
+func (d *countingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.dials.Add(1)
+	return d.Dialer.DialContext(ctx, network, address)
+}
+
+func TestClient_Dialer(t *testing.T) {
+	server := NewServer(&ServerOption{Addr: ":0"})
+	err := server.Start()
+	require.NoError(t, err)
+	defer server.Stop()
+
+	dialer := &countingDialer{}
+	cl := NewClient(&ClientOption{Addr: server.Addr(), Dialer: dialer})
+
+	err = cl.Connect()
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	assert.Equal(t, int32(1), dialer.dials.Load())
+}
@@ -1,19 +1,22 @@
 package tcp
 
 import (
-	"bufio"
 	"fmt"
 	"net"
 	"os"
 	"time"
 
 	"github.com/rs/xid"
+
+	"github.com/codingLayce/tunnel.go/pdu"
 )
 
 type ConnectionOption struct {
 	OnConnectionClosed func(conn *Connection, timeout bool)
 	OnPayload          func(conn *Connection, payload []byte)
 	ReadTimeout        time.Duration
+	Framing            pdu.Framing
+	MaxFrameSize       int
 }
 
 func (opts *ConnectionOption) defaults() {
@@ -25,17 +28,29 @@ func (opts *ConnectionOption) defaults() {
 type Connection struct {
 	net.Conn
 
-	ID   string
-	opts *ConnectionOption
+	ID      string
+	opts    *ConnectionOption
+	decoder *pdu.Decoder
 }
 
 func NewConnection(conn net.Conn, opts *ConnectionOption) *Connection {
 	opts.defaults()
-	return &Connection{
+	connection := &Connection{
 		Conn: conn,
 		ID:   xid.New().String(),
 		opts: opts,
 	}
+	connection.decoder = pdu.NewDecoder(conn, &pdu.DecoderOption{
+		Framing:      opts.Framing,
+		MaxFrameSize: opts.MaxFrameSize,
+	})
+	return connection
+}
+
+// SetFraming changes the framing used to read the next payloads.
+// To switch without misreading a payload, it must be invoked from the OnPayload callback.
+func (c *Connection) SetFraming(framing pdu.Framing) {
+	c.decoder.SetFraming(framing)
 }
 
 func (c *Connection) Send(payload []byte) error {
@@ -44,7 +59,6 @@ func (c *Connection) Send(payload []byte) error {
 }
 
 func (c *Connection) payloadLoop() {
-	reader := bufio.NewReader(c)
 	for {
 		err := c.SetReadDeadline(time.Now().Add(c.opts.ReadTimeout))
 		if err != nil {
@@ -52,7 +66,7 @@ func (c *Connection) payloadLoop() {
 			return
 		}
 
-		payload, err := reader.ReadBytes('\n')
+		payload, err := c.decoder.ReadPayload()
 		switch {
 		case err == nil:
 			c.handlePayload(payload)
@@ -64,12 +78,9 @@ func (c *Connection) payloadLoop() {
 }
 
 func (c *Connection) handleReadError(err error) {
-	timeout := false
-	switch {
-	case os.IsTimeout(err):
-		c.Close()
-		timeout = true
-	}
+	// Closing in any case, the stream cannot be read anymore (timeout, oversized frame, closed by peer, ...)
+	c.Close()
+	timeout := os.IsTimeout(err)
 
 	if c.opts.OnConnectionClosed != nil {
 		c.opts.OnConnectionClosed(c, timeout)
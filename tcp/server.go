@@ -6,6 +6,8 @@ import (
 	"net"
 	"sync"
 	"time"
+
+	"github.com/codingLayce/tunnel.go/pdu"
 )
 
 const defaultReadTimeout = time.Minute
@@ -27,6 +29,13 @@ type ServerOption struct {
 
 	// ReadTimeout is the allowed idle duration before disconnecting the client.
 	ReadTimeout time.Duration
+
+	// Framing used to split the payloads of every connection.
+	Framing pdu.Framing
+
+	// MaxFrameSize is the maximum payload size (in bytes) a connection can send before being disconnected.
+	// 0 means pdu.DefaultMaxFrameSize.
+	MaxFrameSize int
 }
 
 func (opts *ServerOption) defaults() {
@@ -89,6 +98,8 @@ func (s *Server) handleConnection(conn net.Conn) {
 		OnConnectionClosed: s.opts.OnConnectionClosed,
 		OnPayload:          s.opts.OnPayload,
 		ReadTimeout:        s.opts.ReadTimeout,
+		Framing:            s.opts.Framing,
+		MaxFrameSize:       s.opts.MaxFrameSize,
 	})
 	s.storeConnection(connection)
 
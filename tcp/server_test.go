@@ -1,6 +1,7 @@
 package tcp
 
 import (
+	"encoding/binary"
 	"log/slog"
 	"net"
 	"testing"
@@ -8,6 +9,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
 )
 
 // TestServer checks a nominal server case.
@@ -85,3 +89,102 @@ func TestServer(t *testing.T) {
 		assert.FailNow(t, "Server should have stopped")
 	}
 }
+
+func TestServer_LengthPrefixedFraming(t *testing.T) {
+	payloadReceived := make(chan []byte)
+	srv := NewServer(&ServerOption{
+		Addr:    ":0",
+		Framing: pdu.LengthPrefixedFraming,
+		OnPayload: func(_ *Connection, payload []byte) {
+			payloadReceived <- payload
+		},
+	})
+
+	err := srv.Start()
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	conn, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	first := pdu.LengthPrefixedFraming.Marshal(command.NewPublishMessageWithTransactionID("abcd1234", "Bidule", []byte("Multi\nline")))
+	second := pdu.LengthPrefixedFraming.Marshal(command.NewAckWithTransactionID("abcd1234"))
+	_, err = conn.Write(append(append([]byte{}, first...), second...))
+	require.NoError(t, err)
+
+	for _, expected := range [][]byte{first, second} {
+		select {
+		case payload := <-payloadReceived:
+			assert.Equal(t, expected, payload)
+		case <-time.After(50 * time.Millisecond):
+			assert.FailNow(t, "A payload should have been received")
+		}
+	}
+}
+
+func TestServer_MaxFrameSize(t *testing.T) {
+	connectionClosed := make(chan struct{})
+	srv := NewServer(&ServerOption{
+		Addr:         ":0",
+		MaxFrameSize: 16,
+		OnPayload: func(_ *Connection, _ []byte) {
+			assert.Fail(t, "The payload shouldn't have been received")
+		},
+		OnConnectionClosed: func(_ *Connection, timeout bool) {
+			assert.False(t, timeout)
+			close(connectionClosed)
+		},
+	})
+
+	err := srv.Start()
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	conn, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write(pdu.Marshal(command.NewPublishMessageWithTransactionID("abcd1234", "Bidule", []byte("Mon super message"))))
+	require.NoError(t, err)
+
+	select {
+	case <-connectionClosed:
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "The connection should have been closed")
+	}
+}
+
+// TestServer_HugeDeclaredLength checks a declared length above the default max frame size closes the connection
+// without allocating it.
+func TestServer_HugeDeclaredLength(t *testing.T) {
+	connectionClosed := make(chan struct{})
+	srv := NewServer(&ServerOption{
+		Addr:    ":0",
+		Framing: pdu.LengthPrefixedFraming,
+		OnPayload: func(_ *Connection, _ []byte) {
+			assert.Fail(t, "The payload shouldn't have been received")
+		},
+		OnConnectionClosed: func(_ *Connection, timeout bool) {
+			assert.False(t, timeout)
+			close(connectionClosed)
+		},
+	})
+
+	err := srv.Start()
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	conn, err := net.Dial("tcp", srv.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write(binary.AppendUvarint([]byte("=abcd1234"), 1<<50))
+	require.NoError(t, err)
+
+	select {
+	case <-connectionClosed:
+	case <-time.After(50 * time.Millisecond):
+		assert.FailNow(t, "The connection should have been closed")
+	}
+}
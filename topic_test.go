@@ -0,0 +1,128 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu"
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestClient_CreateTTunnel(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.TopicTunnelCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		createTunnel, ok := cmd.(*command.CreateTunnel)
+		if assert.True(t, ok, "Server should have received a CreateTunnel command") {
+			assert.Equal(t, "orders.eu.created", createTunnel.Name)
+			assert.Equal(t, command.TopicTunnel, createTunnel.Type)
+		}
+		return ack(cmd)
+	})
+
+	err = cl.CreateTTunnel("orders.eu.created")
+	require.NoError(t, err)
+}
+
+func TestClient_CreateTTunnel_ValidationError(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.TopicTunnelCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	err = cl.CreateTTunnel("orders..created")
+	assert.EqualError(t, err, "validate command: invalid name")
+}
+
+func TestClient_CreateTTunnel_UnsupportedFeature(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	err = cl.CreateTTunnel("orders.eu.created")
+	assert.EqualError(t, err, "feature not supported by the server: topic_tunnel")
+}
+
+func TestClient_ListenTunnel_Pattern_UnsupportedFeature(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	_, err = cl.ListenTunnel("orders.>", func(_ string) {})
+	assert.ErrorIs(t, err, ErrUnsupportedFeature)
+	assert.False(t, cl.listeners.Has("orders.>"))
+}
+
+func TestClient_ListenTunnel_Pattern(t *testing.T) {
+	tcpClient := newTestTCPClientWithCapabilities(command.TopicTunnelCapability)
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	received := make(chan *Message, 3)
+	callback := func(msg *Message) {
+		received <- msg
+	}
+
+	for _, pattern := range []string{"orders.>", "orders.*.created", "payments.>"} {
+		replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+			listenTunnel, ok := cmd.(*command.ListenTunnel)
+			if assert.True(t, ok, "Server should have received a ListenTunnel command") {
+				assert.Equal(t, pattern, listenTunnel.Name)
+			}
+			return ack(cmd)
+		})
+		_, err = cl.ListenTunnelMessages(pattern, callback)
+		require.NoError(t, err)
+	}
+
+	msg := command.NewReceiveMessage("orders.eu.created", []byte("This is a message"))
+	go tcpClient.callOnPayload(pdu.Marshal(msg))
+
+	// Delivered to both matching Subscriptions.
+	for range 2 {
+		select {
+		case receivedMsg := <-received:
+			assert.Equal(t, "orders.eu.created", receivedMsg.TunnelName)
+			assert.Equal(t, []byte("This is a message"), receivedMsg.Body)
+		case <-time.After(100 * time.Millisecond):
+			assert.FailNow(t, "A message should have been received")
+		}
+	}
+
+	// Acknowledged once.
+	select {
+	case cmd := <-tcpClient.commandsChan():
+		_, isAck := cmd.(*command.Ack)
+		assert.True(t, isAck, "Command should have been ack")
+		assert.Equal(t, msg.TransactionID(), cmd.TransactionID())
+	case <-time.After(100 * time.Millisecond):
+		assert.FailNow(t, "A ack should have been received server side")
+	}
+
+	select {
+	case receivedMsg := <-received:
+		assert.Fail(t, "The message should have been received twice", receivedMsg.TunnelName)
+	case cmd := <-tcpClient.commandsChan():
+		assert.Fail(t, "The message should have been ack once", cmd.Info())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,23 @@
+package tunnel
+
+import "github.com/codingLayce/tunnel.go/pdu/command"
+
+// TunnelInfo describes a Tunnel known by the server.
+type TunnelInfo struct {
+	Name string
+	Type command.TunnelType
+	// Listeners is the number of clients listening the Tunnel.
+	Listeners int
+}
+
+func newTunnelInfos(cmd *command.TunnelInfo) []TunnelInfo {
+	infos := make([]TunnelInfo, 0, len(cmd.Tunnels))
+	for _, tunnel := range cmd.Tunnels {
+		infos = append(infos, TunnelInfo{
+			Name:      tunnel.Name,
+			Type:      tunnel.Type,
+			Listeners: tunnel.Listeners,
+		})
+	}
+	return infos
+}
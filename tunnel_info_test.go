@@ -0,0 +1,137 @@
+package tunnel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/codingLayce/tunnel.go/pdu/command"
+)
+
+func TestClient_ListTunnels(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		listTunnels, ok := cmd.(*command.ListTunnels)
+		if assert.True(t, ok, "Server should have received a ListTunnels command") {
+			assert.Equal(t, "Bid*", listTunnels.Pattern)
+		}
+		return command.NewTunnelInfoWithTransactionID(cmd.TransactionID(),
+			command.TunnelDescription{Name: "Bidule", Type: command.BroadcastTunnel, Listeners: 3},
+			command.TunnelDescription{Name: "Bidon", Type: command.BroadcastTunnel},
+		)
+	})
+
+	tunnels, err := cl.ListTunnels("Bid*")
+	require.NoError(t, err)
+	assert.Equal(t, []TunnelInfo{
+		{Name: "Bidule", Type: command.BroadcastTunnel, Listeners: 3},
+		{Name: "Bidon", Type: command.BroadcastTunnel},
+	}, tunnels)
+}
+
+func TestClient_ListTunnels_Empty(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		return command.NewTunnelInfoWithTransactionID(cmd.TransactionID())
+	})
+
+	tunnels, err := cl.ListTunnels("")
+	require.NoError(t, err)
+	assert.Empty(t, tunnels)
+}
+
+func TestClient_ListTunnels_ValidationError(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	_, err = cl.ListTunnels("Bid?le")
+	assert.EqualError(t, err, "validate command: invalid pattern")
+}
+
+func TestClient_ListTunnels_UnexpectedResponse(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, ack)
+
+	_, err = cl.ListTunnels("")
+	assert.EqualError(t, err, "unexpected response ACK")
+}
+
+func TestClient_DescribeTunnel(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		describeTunnel, ok := cmd.(*command.DescribeTunnel)
+		if assert.True(t, ok, "Server should have received a DescribeTunnel command") {
+			assert.Equal(t, "Bidule", describeTunnel.Name)
+		}
+		return command.NewTunnelInfoWithTransactionID(cmd.TransactionID(),
+			command.TunnelDescription{Name: "Bidule", Type: command.BroadcastTunnel, Listeners: 3},
+		)
+	})
+
+	tunnel, err := cl.DescribeTunnel("Bidule")
+	require.NoError(t, err)
+	assert.Equal(t, &TunnelInfo{Name: "Bidule", Type: command.BroadcastTunnel, Listeners: 3}, tunnel)
+}
+
+func TestClient_DescribeTunnel_NackError(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		nack := command.NewNackWithTransactionID(cmd.TransactionID())
+		nack.Code = command.NackTunnelNotFound
+		return nack
+	})
+
+	_, err = cl.DescribeTunnel("Bidule")
+	assert.ErrorIs(t, err, ErrTunnelNotFound)
+}
+
+func TestClient_DescribeTunnel_UnexpectedResponse(t *testing.T) {
+	tcpClient := newTestTCPClient()
+	mockNewTCPClient(t, tcpClient)
+
+	cl, err := Connect("")
+	require.NoError(t, err)
+	defer cl.Stop()
+
+	replyNext(t, tcpClient, func(cmd command.Command) command.Command {
+		return command.NewTunnelInfoWithTransactionID(cmd.TransactionID())
+	})
+
+	_, err = cl.DescribeTunnel("Bidule")
+	assert.EqualError(t, err, "unexpected response TUNNEL_INFO(0)")
+}